@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/namsral/flag"
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
 	"github.com/stakater/GitWebhookProxy/pkg/proxy"
+	"github.com/stakater/GitWebhookProxy/pkg/replay"
+	"github.com/stakater/GitWebhookProxy/pkg/secrets"
+	"github.com/stakater/GitWebhookProxy/pkg/tracing"
+	"github.com/stakater/GitWebhookProxy/pkg/users"
 )
 
 var (
@@ -15,16 +24,40 @@ var (
 	listenAddress = flagSet.String("listen", ":8080", "Address on which the proxy listens.")
 	upstreamURL   = flagSet.String("upstreamURL", "", "URL to which the proxy requests will be forwarded (required)")
 	secret        = flagSet.String("secret", "", "Secret of the Webhook API. If not set validation is not made.")
+	prevSecrets   = flagSet.String("previousSecrets", "", "Comma-Separated String List of previous secrets still accepted while rotating the secret")
+	secretFile    = flagSet.String("secretFile", "", "File from which the secret, then previous secrets on the following lines, are read, reloaded on change")
 	provider      = flagSet.String("provider", "github", "Git Provider which generates the Webhook")
 	allowedPaths  = flagSet.String("allowedPaths", "", "Comma-Separated String List of allowed paths")
 	ignoredUsers  = flagSet.String("ignoredUsers", "", "Comma-Separated String List of users to ignore while proxying Webhook request")
-	allowedUsers  = flagSet.String("allowedUser", "", "Comma-Separated String List of users to allow while proxying Webhook request")
+	allowedUsers  = flagSet.String("allowedUsers", "", "Comma-Separated String List of users to allow while proxying Webhook request")
+	logLevel      = flagSet.String("logLevel", "info", "Minimum level of logs to write: debug, info, warn or error")
+	logFormat     = flagSet.String("logFormat", logging.FormatJSON, "Format of the logs: json or logfmt")
+	tracingExp    = flagSet.String("tracingExporter", tracing.ExporterNone, "Exporter for OpenTelemetry traces: none, otlp or stdout")
+	historyFile   = flagSet.String("historyFile", "", "File in which recent deliveries are recorded. If not set deliveries are not recorded.")
+	historySize   = flagSet.Int("historyMaxEntries", 1000, "Maximum number of deliveries kept in the history")
+	adminListen   = flagSet.String("adminListen", "127.0.0.1:8081", "Address on which the delivery API and dashboard are served when historyFile is set. If empty they are not served.")
+	probePath     = flagSet.String("upstreamProbePath", "/", "Path on the upstream probed by the readiness check. If empty the upstream is not probed.")
+	probeStatus   = flagSet.Int("upstreamProbeStatus", 0, "Status expected from the upstream probe. If 0 any status below 500 is accepted.")
+	probeInterval = flagSet.Duration("upstreamProbeInterval", 10*time.Second, "Minimum interval between two upstream probes")
+	replayFile    = flagSet.String("replayFile", "", "File in which delivery IDs are remembered to reject replayed deliveries. If not set delivery IDs are not checked.")
+	replayWindow  = flagSet.Duration("replayWindow", 24*time.Hour, "Duration for which delivery IDs are remembered")
+	tsTolerance   = flagSet.Duration("timestampTolerance", 0, "Maximum age of the timestamp carried by payloads. If 0 the timestamp is not checked.")
+	maxBodySize   = flagSet.Int64("maxBodySize", 25<<20, "Maximum size in bytes of webhook payloads. If 0 the size is not limited.")
+	headerTimeout = flagSet.Duration("readHeaderTimeout", 10*time.Second, "Maximum duration for reading the headers of a request")
+	readTimeout   = flagSet.Duration("readTimeout", 30*time.Second, "Maximum duration for reading a whole request")
+	writeTimeout  = flagSet.Duration("writeTimeout", 60*time.Second, "Maximum duration before timing out writes of a response, including forwarding to the upstream")
+	idleTimeout   = flagSet.Duration("idleTimeout", 120*time.Second, "Maximum duration to wait for the next request on a keep-alive connection")
+	maxConcurrent = flagSet.Int("maxConcurrentRequests", 0, "Maximum number of webhooks handled at once. If 0 the number is not limited.")
+	configFile    = flagSet.String("config", "", "YAML file configuring routes and their filters")
+	forwardAll    = flagSet.Bool("forwardAllHeaders", false, "Forward every header of the incoming request instead of the headers of the provider only")
+	groupsFile    = flagSet.String("userGroupsFile", "", "YAML file mapping user group names to their members, reloaded on change")
+	reloadPeriod  = flagSet.Duration("reloadInterval", 10*time.Second, "Interval at which reloadable files are checked for changes")
 )
 
 func validateRequiredFlags() {
 	isValid := true
 	if len(strings.TrimSpace(*upstreamURL)) == 0 {
-		log.Println("Required flag 'upstreamURL' not specified")
+		slog.Error("Required flag 'upstreamURL' not specified")
 		isValid = false
 	}
 
@@ -40,7 +73,18 @@ func validateRequiredFlags() {
 
 func main() {
 	flagSet.Parse(os.Args[1:])
+	if err := logging.Setup(os.Stderr, *logLevel, *logFormat); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	validateRequiredFlags()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), *tracingExp)
+	if err != nil {
+		slog.Error("Error setting up tracing", logging.ErrorKey, err)
+		os.Exit(1)
+	}
+
 	lowerProvider := strings.ToLower(*provider)
 
 	// Split Comma-Separated list into an array
@@ -55,14 +99,120 @@ func main() {
 		ignoredUsersArray = strings.Split(*ignoredUsers, ",")
 	}
 
-	log.Printf("Stakater Git WebHook Proxy started with provider '%s'\n", lowerProvider)
-	p, err := proxy.NewProxy(*upstreamURL, allowedPathsArray, lowerProvider, *secret, ignoredUsersArray)
+	options := []proxy.Option{
+		proxy.WithForwardAllHeaders(*forwardAll),
+		proxy.WithLimits(proxy.Limits{
+			MaxBodyBytes:          *maxBodySize,
+			ReadHeaderTimeout:     *headerTimeout,
+			ReadTimeout:           *readTimeout,
+			WriteTimeout:          *writeTimeout,
+			IdleTimeout:           *idleTimeout,
+			MaxConcurrentRequests: *maxConcurrent,
+		}),
+	}
+	if len(*allowedUsers) > 0 {
+		options = append(options, proxy.WithAllowedUsers(strings.Split(*allowedUsers, ",")))
+	}
+	if len(*prevSecrets) > 0 {
+		options = append(options, proxy.WithPreviousSecrets(strings.Split(*prevSecrets, ",")))
+	}
+	if len(strings.TrimSpace(*configFile)) > 0 {
+		cfg, err := config.Load(*configFile)
+		if err != nil {
+			slog.Error("Error loading config", "file", *configFile, logging.ErrorKey, err)
+			os.Exit(1)
+		}
+		options = append(options, proxy.WithRoutes(cfg.Routes))
+		if cfg.UpstreamAuth != nil {
+			options = append(options, proxy.WithUpstreamAuth(cfg.UpstreamAuth.Authenticator))
+		}
+		if cfg.UpstreamSigningSecret != nil {
+			options = append(options, proxy.WithUpstreamSecret(cfg.UpstreamSigningSecret))
+		}
+		if cfg.SourceRanges != nil {
+			options = append(options, proxy.WithSourceRanges(cfg.SourceRanges.Allowlist))
+		}
+		if cfg.Redaction != nil {
+			options = append(options, proxy.WithRedaction(cfg.Redaction))
+		}
+		options = append(options, proxy.WithTrustedProxies(cfg.TrustedProxyRanges))
+	}
+	var secretStore *secrets.File
+	if len(strings.TrimSpace(*secretFile)) > 0 {
+		if len(*secret) > 0 || len(*prevSecrets) > 0 {
+			slog.Error("Flag 'secretFile' cannot be combined with 'secret' or 'previousSecrets'")
+			os.Exit(1)
+		}
+		secretStore = secrets.NewFile()
+		if err := secretStore.Load(*secretFile); err != nil {
+			slog.Error("Error loading secret", "file", *secretFile, logging.ErrorKey, err)
+			os.Exit(1)
+		}
+		options = append(options, proxy.WithSecretFile(secretStore))
+	}
+	var groups *users.Groups
+	if len(strings.TrimSpace(*groupsFile)) > 0 {
+		groups = users.NewGroups()
+		if err := groups.Load(*groupsFile); err != nil {
+			slog.Error("Error loading user groups", "file", *groupsFile, logging.ErrorKey, err)
+			os.Exit(1)
+		}
+		options = append(options, proxy.WithUserGroups(groups))
+	}
+	if len(strings.TrimSpace(*probePath)) > 0 {
+		options = append(options, proxy.WithUpstreamProbe(*probePath, *probeStatus, *probeInterval))
+	}
+	if len(strings.TrimSpace(*historyFile)) > 0 {
+		store, err := history.Open(*historyFile, *historySize)
+		if err != nil {
+			slog.Error("Error opening delivery history", logging.ErrorKey, err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		options = append(options, proxy.WithHistory(store), proxy.WithAdminListener(*adminListen))
+	}
+	if len(strings.TrimSpace(*replayFile)) > 0 {
+		if len(strings.TrimSpace(*secret)) == 0 && secretStore == nil {
+			slog.Error("Flag 'replayFile' requires 'secret' or 'secretFile', delivery IDs are only remembered for validated hooks")
+			os.Exit(1)
+		}
+		store, err := replay.Open(*replayFile, *replayWindow)
+		if err != nil {
+			slog.Error("Error opening seen delivery store", logging.ErrorKey, err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		options = append(options, proxy.WithReplayProtection(store))
+	}
+	if *tsTolerance > 0 {
+		options = append(options, proxy.WithTimestampTolerance(*tsTolerance))
+	}
+
+	slog.Info("Stakater Git WebHook Proxy started", logging.ProviderKey, lowerProvider)
+	p, err := proxy.NewProxy(*upstreamURL, allowedPathsArray, lowerProvider, *secret, ignoredUsersArray, options...)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("Error creating proxy", logging.ErrorKey, err)
+		os.Exit(1)
 	}
 
-	if err := p.Run(*listenAddress); err != nil {
-		log.Fatal(err)
+	if secretStore != nil {
+		p.ReportConfigLoad("secretFile", nil)
+		go secretStore.Watch(context.Background(), *secretFile, *reloadPeriod, func(err error) {
+			p.ReportConfigLoad("secretFile", err)
+		})
+	}
+	if groups != nil {
+		p.ReportConfigLoad("userGroups", nil)
+		go groups.Watch(context.Background(), *groupsFile, *reloadPeriod, func(err error) {
+			p.ReportConfigLoad("userGroups", err)
+		})
+	}
+
+	err = p.Run(*listenAddress)
+	shutdownTracing(context.Background())
+	if err != nil {
+		slog.Error("Error running proxy", logging.ErrorKey, err)
+		os.Exit(1)
 	}
 
 }
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// Secret returns the current value of a credential, e.g. read from a file or
+// an environment variable, so that rotated credentials are picked up
+type Secret func() (string, error)
+
+// Authenticator attaches credentials to the requests forwarded upstream.
+// Errors never contain the credentials.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// Basic authenticates with a username and a password
+type Basic struct {
+	Username Secret
+	Password Secret
+}
+
+func (a *Basic) Authenticate(req *http.Request) error {
+	username, err := a.Username()
+	if err != nil {
+		return err
+	}
+	password, err := a.Password()
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(username, password)
+	return nil
+}
+
+// Bearer authenticates with a static token
+type Bearer struct {
+	Token Secret
+}
+
+func (a *Bearer) Authenticate(req *http.Request) error {
+	token, err := a.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// TokenTimeout bounds the time taken to fetch an OAuth2 token, so that a hung
+// token endpoint does not hold the forwarded requests waiting for it
+const TokenTimeout = 10 * time.Second
+
+// ClientCredentials authenticates with the tokens of an OAuth2 client
+// credentials grant. Tokens are cached until they expire.
+type ClientCredentials struct {
+	tokens oauth2.TokenSource
+}
+
+func NewClientCredentials(tokenURL string, clientID Secret, clientSecret Secret, scopes []string) *ClientCredentials {
+	return &ClientCredentials{
+		tokens: oauth2.ReuseTokenSource(nil, &clientCredentialsSource{
+			tokenURL:     tokenURL,
+			clientID:     clientID,
+			clientSecret: clientSecret,
+			scopes:       scopes,
+			timeout:      TokenTimeout,
+		}),
+	}
+}
+
+func (a *ClientCredentials) Authenticate(req *http.Request) error {
+	token, err := a.tokens.Token()
+	if err != nil {
+		return err
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
+
+// clientCredentialsSource fetches a new token, reading the client credentials
+// again in case they were rotated
+type clientCredentialsSource struct {
+	tokenURL     string
+	clientID     Secret
+	clientSecret Secret
+	scopes       []string
+	timeout      time.Duration
+}
+
+func (s *clientCredentialsSource) Token() (*oauth2.Token, error) {
+	clientID, err := s.clientID()
+	if err != nil {
+		return nil, err
+	}
+	clientSecret, err := s.clientSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     s.tokenURL,
+		Scopes:       s.scopes,
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: s.timeout})
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	token, err := config.Token(ctx)
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil {
+			// The response body may echo the request, so only the status is kept
+			return nil, errors.New("Error fetching OAuth2 token: " + retrieveErr.Response.Status)
+		}
+		return nil, errors.New("Error fetching OAuth2 token: " + err.Error())
+	}
+	return token, nil
+}
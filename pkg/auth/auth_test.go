@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func staticSecret(value string) Secret {
+	return func() (string, error) {
+		return value, nil
+	}
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:          "TestAuthenticateWithBasic",
+			authenticator: &Basic{Username: staticSecret("jenkins"), Password: staticSecret("s3cr3t")},
+			want:          "Basic amVua2luczpzM2NyM3Q=",
+		},
+		{
+			name:          "TestAuthenticateWithBearer",
+			authenticator: &Bearer{Token: staticSecret("t0k3n")},
+			want:          "Bearer t0k3n",
+		},
+		{
+			name: "TestAuthenticateWithFailingSecret",
+			authenticator: &Bearer{Token: func() (string, error) {
+				return "", errors.New("Environment variable 'TOKEN' is not set")
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			err := tt.authenticator.Authenticate(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authenticate() Authorization = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCredentials_Authenticate(t *testing.T) {
+	fetches := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if clientID, clientSecret, _ := r.BasicAuth(); clientID != "gwp" || clientSecret != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid_client", "client_secret": "` + clientSecret + `"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "t0k3n", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	authenticator := NewClientCredentials(tokenServer.URL, staticSecret("gwp"), staticSecret("s3cr3t"), []string{"jenkins"})
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if err := authenticator.Authenticate(req); err != nil {
+			t.Fatalf("ClientCredentials.Authenticate() error = %v", err)
+		}
+		if got := req.Header.Get("Authorization"); got != "Bearer t0k3n" {
+			t.Errorf("ClientCredentials.Authenticate() Authorization = %v, want Bearer t0k3n", got)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("ClientCredentials.Authenticate() fetched %v tokens, want 1", fetches)
+	}
+
+	authenticator = NewClientCredentials(tokenServer.URL, staticSecret("gwp"), staticSecret("wrong-secret"), nil)
+	err := authenticator.Authenticate(httptest.NewRequest(http.MethodPost, "/", nil))
+	if err == nil {
+		t.Fatalf("ClientCredentials.Authenticate() with invalid client did not return an error")
+	}
+	if strings.Contains(err.Error(), "wrong-secret") {
+		t.Errorf("ClientCredentials.Authenticate() error contains the client secret: %v", err)
+	}
+}
+
+func TestClientCredentials_AuthenticateWithHungTokenEndpoint(t *testing.T) {
+	release := make(chan struct{})
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer tokenServer.Close()
+	defer close(release)
+
+	authenticator := &ClientCredentials{tokens: &clientCredentialsSource{
+		tokenURL:     tokenServer.URL,
+		clientID:     staticSecret("gwp"),
+		clientSecret: staticSecret("s3cr3t"),
+		timeout:      50 * time.Millisecond,
+	}}
+	done := make(chan error)
+	go func() { done <- authenticator.Authenticate(httptest.NewRequest(http.MethodPost, "/", nil)) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("ClientCredentials.Authenticate() with hung token endpoint did not return an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ClientCredentials.Authenticate() with hung token endpoint did not time out")
+	}
+}
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/auth"
+	"github.com/stakater/GitWebhookProxy/pkg/filters"
+	"github.com/stakater/GitWebhookProxy/pkg/network"
+	"github.com/stakater/GitWebhookProxy/pkg/redaction"
+	"github.com/stakater/GitWebhookProxy/pkg/users"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the settings that are too rich for flags, such as per path routes
+type Config struct {
+	// UpstreamAuth authenticates the requests forwarded on every path
+	UpstreamAuth *Auth `yaml:"upstreamAuth"`
+	// UpstreamSecret signs the hooks forwarded on every path again, so that
+	// the upstream does not need the secret of the provider. It is a
+	// template, like the values of an upstream.
+	UpstreamSecret string `yaml:"upstreamSecret"`
+	// SourceRanges only accepts the webhooks sent from its ranges on every path
+	SourceRanges *SourceRanges `yaml:"sourceRanges"`
+	// TrustedProxies lists the ranges of the proxies in front of the proxy,
+	// whose X-Forwarded-For header is used to find the source of webhooks
+	TrustedProxies []string `yaml:"trustedProxies"`
+	// Redaction redacts the payloads forwarded and recorded on every path
+	Redaction *Redaction `yaml:"redaction"`
+	Routes    []*Route   `yaml:"routes"`
+
+	// UpstreamSigningSecret and TrustedProxyRanges are compiled from the
+	// settings above when the config is loaded
+	UpstreamSigningSecret auth.Secret        `yaml:"-"`
+	TrustedProxyRanges    *network.Allowlist `yaml:"-"`
+}
+
+// Route attaches settings to the webhooks received on a path
+type Route struct {
+	Path string `yaml:"path"`
+	// Match is how Path matches incoming paths: prefix, the default, exact or regex
+	Match string `yaml:"match"`
+	// Rewrite changes the path forwarded to the upstream, see RewritePath
+	Rewrite *string `yaml:"rewrite"`
+
+	Events   *EventRules   `yaml:"events"`
+	Refs     *RefPatterns  `yaml:"refs"`
+	HeadRefs *RefPatterns  `yaml:"headRefs"`
+	Files    *FilePatterns `yaml:"files"`
+	SkipCI   *SkipCI       `yaml:"skipCI"`
+	Rules    *Rules        `yaml:"rules"`
+
+	// SkipDrafts drops hooks about draft pull and merge requests
+	SkipDrafts bool `yaml:"skipDrafts"`
+	// SkipBots drops hooks sent on behalf of bot accounts
+	SkipBots bool `yaml:"skipBots"`
+
+	// Upstream changes the requests forwarded to the upstream
+	Upstream *Upstream `yaml:"upstream"`
+
+	// Users lists the users whose hooks are ignored or allowed on the route
+	Users *UserPolicy `yaml:"users"`
+
+	// SourceRanges overrides the sourceRanges of the config for the route
+	SourceRanges *SourceRanges `yaml:"sourceRanges"`
+
+	// Redaction overrides the redaction of the config for the route
+	Redaction *Redaction `yaml:"redaction"`
+
+	// RateLimits limit the hooks forwarded on the route, see RateLimit
+	RateLimits []*RateLimit `yaml:"rateLimits"`
+	// Debounce holds the pushes to a branch for this duration, from the first
+	// one, and only forwards the latest push received meanwhile
+	Debounce time.Duration `yaml:"debounce"`
+
+	// Filters and user lists are compiled from the settings above when the config is loaded
+	Filters      []filters.Filter `yaml:"-"`
+	IgnoredUsers *users.List      `yaml:"-"`
+	AllowedUsers *users.List      `yaml:"-"`
+	pathRegex    *regexp.Regexp
+}
+
+// UserPolicy lists users as globs, regular expressions prefixed with "regex:"
+// or named groups prefixed with "group:". When allow is set, only the hooks of
+// its users are forwarded.
+type UserPolicy struct {
+	Ignore []string `yaml:"ignore"`
+	Allow  []string `yaml:"allow"`
+}
+
+// SourceRanges lists the CIDR ranges, or single addresses, webhooks may be
+// sent from, inline or in files. Files may be a copy of the GitHub meta API
+// response, or list a range per line.
+type SourceRanges struct {
+	Allow []string `yaml:"allow"`
+	Files []string `yaml:"files"`
+
+	// Allowlist is compiled from the settings above when the config is loaded
+	Allowlist *network.Allowlist `yaml:"-"`
+}
+
+// Redaction lists the JSON paths of payload fields to redact and to remove,
+// such as "commits[*].author.email", before hooks are forwarded or recorded
+type Redaction struct {
+	Redact []string `yaml:"redact"`
+	Remove []string `yaml:"remove"`
+	// Resign signs the redacted hooks again with the secret of the provider,
+	// unless an upstream secret signs them, so that the upstream can still
+	// validate them
+	Resign bool `yaml:"resign"`
+
+	// Rules are compiled from the settings above when the config is loaded
+	Rules *redaction.Rules `yaml:"-"`
+}
+
+// EventRules lists the events to allow and deny, as "event", "event:action"
+// or "event:{action1,action2}"
+type EventRules struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// RefPatterns lists the git references to include and exclude
+type RefPatterns struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// FilePatterns lists the changed files to include and exclude
+type FilePatterns struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// SkipCI drops pushes whose head commit message contains one of the tokens,
+// "[skip ci]" and "[ci skip]" by default
+type SkipCI struct {
+	Tokens []string `yaml:"tokens"`
+}
+
+// Rules lists expressions over the payload, headers and derived fields of
+// hooks, allowing and denying them
+type Rules struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Load reads and validates the config file at path
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes a YAML config, rejecting unknown fields, and compiles its filters
+func Parse(data []byte) (*Config, error) {
+	config := &Config{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if err := config.UpstreamAuth.compile(); err != nil {
+		return nil, err
+	}
+	var err error
+	if config.UpstreamSigningSecret, err = compileSigningSecret(config.UpstreamSecret); err != nil {
+		return nil, err
+	}
+	if err := config.SourceRanges.compile(); err != nil {
+		return nil, err
+	}
+	if config.TrustedProxyRanges, err = network.ParseAllowlist(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("Invalid trusted proxies: %s", err)
+	}
+	if err := config.Redaction.compile(); err != nil {
+		return nil, err
+	}
+	for i, route := range config.Routes {
+		if err := route.compile(); err != nil {
+			return nil, fmt.Errorf("Invalid route %d '%s': %s", i, route.Path, err)
+		}
+	}
+	return config, nil
+}
+
+func (s *SourceRanges) compile() error {
+	if s == nil {
+		return nil
+	}
+
+	var err error
+	if s.Allowlist, err = network.ParseAllowlist(s.Allow); err != nil {
+		return fmt.Errorf("Invalid source ranges: %s", err)
+	}
+	for _, file := range s.Files {
+		if err := s.Allowlist.Load(file); err != nil {
+			return fmt.Errorf("Invalid source ranges: %s", err)
+		}
+	}
+	if s.Allowlist.Empty() {
+		return errors.New("Invalid source ranges: at least one range must be allowed")
+	}
+	return nil
+}
+
+func (r *Redaction) compile() error {
+	if r == nil {
+		return nil
+	}
+
+	var err error
+	if r.Rules, err = redaction.Compile(r.Redact, r.Remove); err != nil {
+		return fmt.Errorf("Invalid redaction: %s", err)
+	}
+	return nil
+}
+
+// Paths returns the paths of all routes
+func (c *Config) Paths() []string {
+	paths := []string{}
+	for _, route := range c.Routes {
+		paths = append(paths, route.Path)
+	}
+	return paths
+}
+
+func (r *Route) compile() error {
+	if err := r.compilePath(); err != nil {
+		return err
+	}
+	if err := r.Upstream.compile(); err != nil {
+		return err
+	}
+	if err := r.SourceRanges.compile(); err != nil {
+		return err
+	}
+	if err := r.Redaction.compile(); err != nil {
+		return err
+	}
+	for _, limit := range r.RateLimits {
+		if err := limit.compile(); err != nil {
+			return err
+		}
+	}
+	if r.Debounce < 0 {
+		return errors.New("Debounce cannot be negative")
+	}
+
+	if r.Users != nil {
+		var err error
+		if r.IgnoredUsers, err = users.Compile(r.Users.Ignore); err != nil {
+			return err
+		}
+		if r.AllowedUsers, err = users.Compile(r.Users.Allow); err != nil {
+			return err
+		}
+	}
+
+	r.Filters = []filters.Filter{}
+	if r.Events != nil {
+		filter, err := filters.NewEventFilter(r.Events.Allow, r.Events.Deny)
+		if err != nil {
+			return err
+		}
+		r.Filters = append(r.Filters, filter)
+	}
+	if r.SkipBots {
+		r.Filters = append(r.Filters, filters.NewBotFilter())
+	}
+	if r.SkipDrafts {
+		r.Filters = append(r.Filters, filters.NewDraftFilter())
+	}
+	if r.Refs != nil {
+		filter, err := filters.NewRefFilter(r.Refs.Include, r.Refs.Exclude)
+		if err != nil {
+			return err
+		}
+		r.Filters = append(r.Filters, filter)
+	}
+	if r.HeadRefs != nil {
+		filter, err := filters.NewHeadRefFilter(r.HeadRefs.Include, r.HeadRefs.Exclude)
+		if err != nil {
+			return err
+		}
+		r.Filters = append(r.Filters, filter)
+	}
+	if r.Files != nil {
+		filter, err := filters.NewChangedFilesFilter(r.Files.Include, r.Files.Exclude)
+		if err != nil {
+			return err
+		}
+		r.Filters = append(r.Filters, filter)
+	}
+	if r.SkipCI != nil {
+		r.Filters = append(r.Filters, filters.NewSkipCIFilter(r.SkipCI.Tokens))
+	}
+	if r.Rules != nil {
+		filter, err := filters.NewRuleFilter(r.Rules.Allow, r.Rules.Deny)
+		if err != nil {
+			return err
+		}
+		r.Filters = append(r.Filters, filter)
+	}
+	return nil
+}
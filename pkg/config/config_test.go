@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantPaths   []string
+		wantFilters []int
+		wantErr     bool
+	}{
+		{
+			name:        "TestParseWithEmptyConfig",
+			data:        "",
+			wantPaths:   []string{},
+			wantFilters: []int{},
+		},
+		{
+			name: "TestParseWithFilters",
+			data: `
+routes:
+  - path: /jenkins
+    events:
+      allow: ["push", "pull_request:{opened,synchronize}"]
+    refs:
+      include: ["main", "release/*"]
+    headRefs:
+      exclude: ["dependabot/**"]
+  - path: /docs
+    skipDrafts: true
+    skipBots: true
+  - path: /job/billing
+    files:
+      include: ["services/billing/**"]
+      exclude: ["**/*.md"]
+    skipCI: {}
+    rules:
+      deny: ["committer == 'jenkins'"]
+`,
+			wantPaths:   []string{"/jenkins", "/docs", "/job/billing"},
+			wantFilters: []int{3, 2, 3},
+		},
+		{
+			name:    "TestParseWithUnknownField",
+			data:    "routes:\n  - path: /jenkins\n    branches: [main]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithEmptyPath",
+			data:    "routes:\n  - refs:\n      include: [main]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidEventRule",
+			data:    "routes:\n  - path: /jenkins\n    events:\n      allow: [\"push:\"]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidRule",
+			data:    "routes:\n  - path: /jenkins\n    rules:\n      deny: [\"payload.draft ==\"]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithUnknownMatchMode",
+			data:    "routes:\n  - path: /jenkins\n    match: glob\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidPathRegex",
+			data:    "routes:\n  - path: /jenkins/(\n    match: regex\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithSeveralUpstreamAuths",
+			data:    "upstreamAuth:\n  bearer: {token: abc}\n  basic: {username: a, password: b}\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithOAuth2WithoutTokenURL",
+			data:    "routes:\n  - path: /jenkins\n    upstream:\n      auth:\n        oauth2: {clientID: gwp}\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidUpstreamSecret",
+			data:    "upstreamSecret: '{{ env }'\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidSourceRange",
+			data:    "sourceRanges:\n  allow: [140.82.112.0/33]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithEmptySourceRanges",
+			data:    "routes:\n  - path: /jenkins\n    sourceRanges: {allow: []}\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithMissingSourceRangesFile",
+			data:    "sourceRanges:\n  files: [/nonexistent/github-meta.json]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidTrustedProxy",
+			data:    "trustedProxies: [ingress]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidRedaction",
+			data:    "redaction:\n  redact: [\"commits[*.author.email\"]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidRouteRedaction",
+			data:    "routes:\n  - path: /jenkins\n    redaction:\n      remove: [\"pull_request..body\"]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithNegativeDebounce",
+			data:    "routes:\n  - path: /jenkins\n    debounce: -1m\n",
+			wantErr: true,
+		},
+		{
+			name:    "TestParseWithInvalidRegex",
+			data:    "routes:\n  - path: /jenkins\n    refs:\n      include: [\"regex:(\"]\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.Paths(), tt.wantPaths) {
+				t.Errorf("Parse() paths = %v, want %v", got.Paths(), tt.wantPaths)
+			}
+			gotFilters := []int{}
+			for _, route := range got.Routes {
+				gotFilters = append(gotFilters, len(route.Filters))
+			}
+			if !reflect.DeepEqual(gotFilters, tt.wantFilters) {
+				t.Errorf("Parse() filters = %v, want %v", gotFilters, tt.wantFilters)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("routes:\n  - path: /jenkins\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Paths(), []string{"/jenkins"}) {
+		t.Errorf("Load() paths = %v, want [/jenkins]", got.Paths())
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("Load() with missing file expected an error")
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Modes of matching the path of a route
+const (
+	MatchPrefix = "prefix"
+	MatchExact  = "exact"
+	MatchRegex  = "regex"
+)
+
+// compilePath validates the match mode and compiles regex paths
+func (r *Route) compilePath() error {
+	if len(strings.TrimSpace(r.Path)) == 0 {
+		return errors.New("Route path cannot be empty")
+	}
+
+	switch r.Match {
+	case "", MatchPrefix, MatchExact:
+		return nil
+	case MatchRegex:
+		re, err := regexp.Compile("^(?:" + r.Path + ")$")
+		if err != nil {
+			return err
+		}
+		r.pathRegex = re
+		return nil
+	default:
+		return errors.New("Unknown match mode '" + r.Match + "'")
+	}
+}
+
+// MatchPath tells whether path matches the route, and how specific the match
+// is. Exact matches are the most specific, then prefixes by length, then
+// regular expressions.
+func (r *Route) MatchPath(path string) (int, bool) {
+	switch r.Match {
+	case MatchExact:
+		return math.MaxInt32, trimPath(path) == trimPath(r.Path)
+	case MatchRegex:
+		return 0, r.pathRegex.MatchString(path)
+	default:
+		// Prefixes end at a segment boundary, so /project does not match /projectX
+		prefix := trimPath(r.Path)
+		incomingPath := trimPath(path)
+		return len(prefix) + 1, incomingPath == prefix || strings.HasPrefix(incomingPath, prefix+"/")
+	}
+}
+
+// RewritePath returns the path to forward a hook received on path to, which
+// may include a query. Without rewrite the path is kept. With rewrite, exact
+// routes forward to rewrite, prefix routes replace the prefix with rewrite,
+// and regex routes expand the capture groups in rewrite, e.g. $1 or ${name}.
+// The parts taken from path are escaped, so that they cannot add a query.
+func (r *Route) RewritePath(path string) string {
+	if r.Rewrite == nil {
+		return escapePath(path)
+	}
+
+	switch r.Match {
+	case MatchExact:
+		return *r.Rewrite
+	case MatchRegex:
+		return r.expandPath(path)
+	default:
+		rest := escapePath(strings.TrimPrefix(trimPath(path), trimPath(r.Path)))
+		rewritten := *r.Rewrite + rest
+		if strings.HasSuffix(*r.Rewrite, "/") && rest != "" {
+			rewritten = *r.Rewrite + strings.TrimPrefix(rest, "/")
+		}
+		if rewritten == "" {
+			return "/"
+		}
+		return rewritten
+	}
+}
+
+// expandPath expands the capture groups of the regex matching path in rewrite.
+// Groups are path escaped before the query of rewrite and query escaped in it.
+func (r *Route) expandPath(path string) string {
+	match := r.pathRegex.FindStringSubmatchIndex(path)
+	if match == nil {
+		return path
+	}
+
+	rewritePath, rewriteQuery, hasQuery := strings.Cut(*r.Rewrite, "?")
+	expanded := string(r.expand(path, match, rewritePath, escapePath))
+	if hasQuery {
+		expanded += "?" + string(r.expand(path, match, rewriteQuery, url.QueryEscape))
+	}
+	return expanded
+}
+
+// expand expands template with the groups of match in path, each escaped
+func (r *Route) expand(path string, match []int, template string, escape func(string) string) []byte {
+	escaped := ""
+	escapedMatch := make([]int, len(match))
+	for i := 0; i < len(match); i += 2 {
+		if match[i] < 0 {
+			escapedMatch[i], escapedMatch[i+1] = -1, -1
+			continue
+		}
+		escapedMatch[i] = len(escaped)
+		escaped += escape(path[match[i]:match[i+1]])
+		escapedMatch[i+1] = len(escaped)
+	}
+	return r.pathRegex.ExpandString(nil, template, escaped, escapedMatch)
+}
+
+// escapePath escapes path to be used in a URL, keeping its slashes
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+func trimPath(path string) string {
+	return strings.TrimSuffix(strings.TrimSpace(path), "/")
+}
@@ -0,0 +1,88 @@
+package config
+
+import "testing"
+
+func parseRoute(t *testing.T, data string) *Route {
+	config, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.Routes[0]
+}
+
+func TestRoute_MatchPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		route string
+		path  string
+		want  bool
+	}{
+		{name: "TestMatchPathWithPrefix", route: "path: /project", path: "/project/job", want: true},
+		{name: "TestMatchPathWithPrefixAcrossSegment", route: "path: /project", path: "/projectX/job", want: false},
+		{name: "TestMatchPathWithPrefixTrailingSlash", route: "path: /project/", path: "/project", want: true},
+		{name: "TestMatchPathWithExact", route: "{path: /project, match: exact}", path: "/project/", want: true},
+		{name: "TestMatchPathWithExactSubPath", route: "{path: /project, match: exact}", path: "/project/job", want: false},
+		{name: "TestMatchPathWithRegex", route: "{path: '/hooks/(.*)', match: regex}", path: "/hooks/abc", want: true},
+		{name: "TestMatchPathWithAnchoredRegex", route: "{path: '/hooks/[a-z]+', match: regex}", path: "/x/hooks/abc", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := parseRoute(t, "routes:\n  - "+tt.route+"\n")
+			if _, got := route.MatchPath(tt.path); got != tt.want {
+				t.Errorf("Route.MatchPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoute_RewritePath(t *testing.T) {
+	tests := []struct {
+		name  string
+		route string
+		path  string
+		want  string
+	}{
+		{name: "TestRewritePathWithoutRewrite", route: "path: /project", path: "/project/job", want: "/project/job"},
+		{name: "TestRewritePathWithPrefix", route: "{path: /github, rewrite: /jenkins/github-webhook/}", path: "/github/x", want: "/jenkins/github-webhook/x"},
+		{name: "TestRewritePathWithStrippedPrefix", route: "{path: /github, rewrite: ''}", path: "/github/", want: "/"},
+		{name: "TestRewritePathWithExact", route: "{path: /build, match: exact, rewrite: /job/build/build}", path: "/build", want: "/job/build/build"},
+		{
+			name:  "TestRewritePathWithRegexCaptureGroup",
+			route: "{path: '/hooks/(.*)', match: regex, rewrite: '/generic-webhook-trigger/invoke?token=$1'}",
+			path:  "/hooks/my-token",
+			want:  "/generic-webhook-trigger/invoke?token=my-token",
+		},
+		{
+			name:  "TestRewritePathWithRegexNamedGroup",
+			route: "{path: '/(?P<team>[a-z]+)/hook', match: regex, rewrite: '/job/${team}-build/build'}",
+			path:  "/billing/hook",
+			want:  "/job/billing-build/build",
+		},
+		{
+			name:  "TestRewritePathWithRegexCaptureGroupInjectingQuery",
+			route: "{path: '/hooks/(.*)', match: regex, rewrite: '/generic-webhook-trigger/invoke?token=$1'}",
+			path:  "/hooks/abc&cause=x",
+			want:  "/generic-webhook-trigger/invoke?token=abc%26cause%3Dx",
+		},
+		{
+			name:  "TestRewritePathWithRegexCaptureGroupInjectingPathQuery",
+			route: "{path: '/hooks/(.*)', match: regex, rewrite: '/job/$1/build'}",
+			path:  "/hooks/a/b?token=x",
+			want:  "/job/a/b%3Ftoken=x/build",
+		},
+		{
+			name:  "TestRewritePathWithPrefixInjectingQuery",
+			route: "{path: /github, rewrite: /jenkins/github-webhook/}",
+			path:  "/github/x?token=y",
+			want:  "/jenkins/github-webhook/x%3Ftoken=y",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := parseRoute(t, "routes:\n  - "+tt.route+"\n")
+			if got := route.RewritePath(tt.path); got != tt.want {
+				t.Errorf("Route.RewritePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/ratelimit"
+)
+
+// Keys by which hooks are rate limited
+const (
+	RateLimitByRepository = "repository"
+	RateLimitBySender     = "sender"
+	RateLimitBySource     = "source"
+)
+
+// Actions taken on the hooks exceeding a rate limit
+const (
+	RateLimitReject = "reject"
+	RateLimitDelay  = "delay"
+)
+
+// RateLimit allows Limit hooks Per period for each repository, sender or
+// source address, with bursts of up to Burst hooks. Hooks exceeding it are
+// rejected, or delayed by up to MaxDelay.
+type RateLimit struct {
+	Key      string        `yaml:"key"`
+	Limit    int           `yaml:"limit"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+	Exceeded string        `yaml:"exceeded"`
+	MaxDelay time.Duration `yaml:"maxDelay"`
+
+	// Limiter is compiled from the settings above when the config is loaded
+	Limiter *ratelimit.Limiter `yaml:"-"`
+}
+
+func (l *RateLimit) compile() error {
+	switch l.Key {
+	case RateLimitByRepository, RateLimitBySender, RateLimitBySource:
+	default:
+		return fmt.Errorf("Invalid rate limit: unknown key '%s'", l.Key)
+	}
+	if l.Limit <= 0 || l.Per <= 0 {
+		return errors.New("Invalid rate limit: limit and per must be positive")
+	}
+	if l.Burst < 0 {
+		return errors.New("Invalid rate limit: burst cannot be negative")
+	}
+	if l.Burst == 0 {
+		l.Burst = 1
+	}
+
+	switch l.Exceeded {
+	case "", RateLimitReject:
+		l.Exceeded = RateLimitReject
+		l.MaxDelay = 0
+	case RateLimitDelay:
+		if l.MaxDelay <= 0 {
+			return errors.New("Invalid rate limit: maxDelay must be positive to delay hooks")
+		}
+	default:
+		return fmt.Errorf("Invalid rate limit: unknown action '%s'", l.Exceeded)
+	}
+
+	l.Limiter = ratelimit.NewLimiter(l.Limit, l.Per, l.Burst)
+	return nil
+}
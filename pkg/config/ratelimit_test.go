@@ -0,0 +1,81 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimit_compile(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantBurst    int
+		wantExceeded string
+		wantMaxDelay time.Duration
+		wantErr      bool
+	}{
+		{
+			name:         "TestCompileWithDefaults",
+			data:         "{key: repository, limit: 60, per: 1h}",
+			wantBurst:    1,
+			wantExceeded: RateLimitReject,
+		},
+		{
+			name:         "TestCompileWithDelay",
+			data:         "{key: sender, limit: 10, per: 1m, burst: 5, exceeded: delay, maxDelay: 10m}",
+			wantBurst:    5,
+			wantExceeded: RateLimitDelay,
+			wantMaxDelay: 10 * time.Minute,
+		},
+		{
+			name:         "TestCompileIgnoresMaxDelayOfReject",
+			data:         "{key: source, limit: 10, per: 1m, exceeded: reject, maxDelay: 10m}",
+			wantBurst:    1,
+			wantExceeded: RateLimitReject,
+		},
+		{
+			name:    "TestCompileWithUnknownKey",
+			data:    "{key: branch, limit: 10, per: 1m}",
+			wantErr: true,
+		},
+		{
+			name:    "TestCompileWithoutPeriod",
+			data:    "{key: repository, limit: 10}",
+			wantErr: true,
+		},
+		{
+			name:    "TestCompileWithNegativeBurst",
+			data:    "{key: repository, limit: 10, per: 1m, burst: -1}",
+			wantErr: true,
+		},
+		{
+			name:    "TestCompileDelayWithoutMaxDelay",
+			data:    "{key: repository, limit: 10, per: 1m, exceeded: delay}",
+			wantErr: true,
+		},
+		{
+			name:    "TestCompileWithUnknownAction",
+			data:    "{key: repository, limit: 10, per: 1m, exceeded: drop}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte("routes:\n  - path: /jenkins\n    rateLimits: [" + tt.data + "]\n"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := cfg.Routes[0].RateLimits[0]
+			if got.Burst != tt.wantBurst || got.Exceeded != tt.wantExceeded || got.MaxDelay != tt.wantMaxDelay {
+				t.Errorf("RateLimit = %v %v %v, want %v %v %v", got.Burst, got.Exceeded, got.MaxDelay,
+					tt.wantBurst, tt.wantExceeded, tt.wantMaxDelay)
+			}
+			if got.Limiter == nil {
+				t.Errorf("RateLimit.Limiter was not compiled")
+			}
+		})
+	}
+}
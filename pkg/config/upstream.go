@@ -0,0 +1,233 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/stakater/GitWebhookProxy/pkg/auth"
+)
+
+// Upstream changes the requests a route forwards to the upstream
+type Upstream struct {
+	// ForwardAllHeaders overrides the forwardAllHeaders flag for the route
+	ForwardAllHeaders *bool `yaml:"forwardAllHeaders"`
+	// Headers and Query are changed on the forwarded request
+	Headers *Values `yaml:"headers"`
+	Query   *Values `yaml:"query"`
+	// Auth overrides the upstreamAuth of the config for the route
+	Auth *Auth `yaml:"auth"`
+	// Secret overrides the upstreamSecret of the config for the route
+	Secret string `yaml:"secret"`
+
+	// SigningSecret is compiled from Secret when the config is loaded
+	SigningSecret auth.Secret `yaml:"-"`
+}
+
+// Auth configures how forwarded requests authenticate to the upstream, with
+// exactly one of basic, bearer or oauth2. Credentials are templates, like
+// Values, rendered whenever they are needed.
+type Auth struct {
+	Basic *struct {
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"basic"`
+	Bearer *struct {
+		Token string `yaml:"token"`
+	} `yaml:"bearer"`
+	OAuth2 *struct {
+		TokenURL     string   `yaml:"tokenURL"`
+		ClientID     string   `yaml:"clientID"`
+		ClientSecret string   `yaml:"clientSecret"`
+		Scopes       []string `yaml:"scopes"`
+	} `yaml:"oauth2"`
+
+	// Authenticator is compiled from the settings above when the config is loaded
+	Authenticator auth.Authenticator `yaml:"-"`
+}
+
+// Values sets, adds and removes headers or query parameters. Values are
+// templates, which can read environment variables with {{ env "NAME" }} and
+// secret files with {{ file "/path" }}. They are rendered for every request,
+// so rotated files are picked up.
+type Values struct {
+	Set    map[string]string `yaml:"set"`
+	Add    map[string]string `yaml:"add"`
+	Remove []string          `yaml:"remove"`
+
+	set map[string]*template.Template
+	add map[string]*template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"env": func(name string) (string, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.New("Environment variable '" + name + "' is not set")
+		}
+		return value, nil
+	},
+	"file": func(path string) (string, error) {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(content)), nil
+	},
+}
+
+func (u *Upstream) compile() error {
+	if u == nil {
+		return nil
+	}
+	if err := u.Headers.compile(); err != nil {
+		return fmt.Errorf("Invalid upstream headers: %s", err)
+	}
+	if err := u.Query.compile(); err != nil {
+		return fmt.Errorf("Invalid upstream query: %s", err)
+	}
+	var err error
+	if u.SigningSecret, err = compileSigningSecret(u.Secret); err != nil {
+		return err
+	}
+	return u.Auth.compile()
+}
+
+func (a *Auth) compile() error {
+	if a == nil {
+		return nil
+	}
+
+	configured := 0
+	for _, set := range []bool{a.Basic != nil, a.Bearer != nil, a.OAuth2 != nil} {
+		if set {
+			configured++
+		}
+	}
+	if configured != 1 {
+		return errors.New("Invalid upstream auth: exactly one of basic, bearer or oauth2 must be set")
+	}
+
+	var err error
+	switch {
+	case a.Basic != nil:
+		basic := &auth.Basic{}
+		if basic.Username, err = compileSecret("auth username", a.Basic.Username); err != nil {
+			return err
+		}
+		if basic.Password, err = compileSecret("auth password", a.Basic.Password); err != nil {
+			return err
+		}
+		a.Authenticator = basic
+	case a.Bearer != nil:
+		bearer := &auth.Bearer{}
+		if bearer.Token, err = compileSecret("auth token", a.Bearer.Token); err != nil {
+			return err
+		}
+		a.Authenticator = bearer
+	default:
+		if a.OAuth2.TokenURL == "" {
+			return errors.New("Invalid upstream auth: oauth2 tokenURL cannot be empty")
+		}
+		clientID, err := compileSecret("auth clientID", a.OAuth2.ClientID)
+		if err != nil {
+			return err
+		}
+		clientSecret, err := compileSecret("auth clientSecret", a.OAuth2.ClientSecret)
+		if err != nil {
+			return err
+		}
+		a.Authenticator = auth.NewClientCredentials(a.OAuth2.TokenURL, clientID, clientSecret, a.OAuth2.Scopes)
+	}
+	return nil
+}
+
+// compileSecret compiles a credential template
+func compileSecret(name string, value string) (auth.Secret, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid upstream %s: %s", name, err)
+	}
+	return func() (string, error) {
+		var rendered bytes.Buffer
+		if err := tmpl.Execute(&rendered, nil); err != nil {
+			return "", err
+		}
+		return rendered.String(), nil
+	}, nil
+}
+
+// compileSigningSecret compiles the template of the secret with which
+// forwarded hooks are signed again, if any
+func compileSigningSecret(value string) (auth.Secret, error) {
+	if len(strings.TrimSpace(value)) == 0 {
+		return nil, nil
+	}
+	return compileSecret("secret", value)
+}
+
+func (v *Values) compile() error {
+	if v == nil {
+		return nil
+	}
+
+	var err error
+	if v.set, err = compileTemplates(v.Set); err != nil {
+		return err
+	}
+	v.add, err = compileTemplates(v.Add)
+	return err
+}
+
+func compileTemplates(values map[string]string) (map[string]*template.Template, error) {
+	templates := map[string]*template.Template{}
+	for name, value := range values {
+		tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = tmpl
+	}
+	return templates, nil
+}
+
+// Removed returns the names of the values to remove
+func (v *Values) Removed() []string {
+	if v == nil {
+		return nil
+	}
+	return v.Remove
+}
+
+// Render returns the values to set and to add
+func (v *Values) Render() (map[string]string, map[string]string, error) {
+	if v == nil {
+		return nil, nil, nil
+	}
+
+	set, err := renderTemplates(v.set)
+	if err != nil {
+		return nil, nil, err
+	}
+	add, err := renderTemplates(v.add)
+	if err != nil {
+		return nil, nil, err
+	}
+	return set, add, nil
+}
+
+func renderTemplates(templates map[string]*template.Template) (map[string]string, error) {
+	values := map[string]string{}
+	for name, tmpl := range templates {
+		var value bytes.Buffer
+		if err := tmpl.Execute(&value, nil); err != nil {
+			return nil, err
+		}
+		values[name] = value.String()
+	}
+	return values, nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValues_Render(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GWP_TEST_TOKEN", "env-token")
+
+	tests := []struct {
+		name        string
+		values      string
+		wantSet     map[string]string
+		wantAdd     map[string]string
+		wantErr     bool
+		wantLoadErr bool
+	}{
+		{
+			name:    "TestRenderWithStaticValues",
+			values:  "{set: {token: abc}, add: {X-Source: gwp}}",
+			wantSet: map[string]string{"token": "abc"},
+			wantAdd: map[string]string{"X-Source": "gwp"},
+		},
+		{
+			name:    "TestRenderWithEnvAndFile",
+			values:  "{set: {Authorization: 'Bearer {{ env \"GWP_TEST_TOKEN\" }}', token: '{{ file \"" + tokenFile + "\" }}'}}",
+			wantSet: map[string]string{"Authorization": "Bearer env-token", "token": "file-token"},
+			wantAdd: map[string]string{},
+		},
+		{
+			name:    "TestRenderWithMissingEnv",
+			values:  "{set: {token: '{{ env \"GWP_TEST_MISSING\" }}'}}",
+			wantErr: true,
+		},
+		{
+			name:    "TestRenderWithMissingFile",
+			values:  "{set: {token: '{{ file \"/nonexistent/token\" }}'}}",
+			wantErr: true,
+		},
+		{
+			name:        "TestRenderWithInvalidTemplate",
+			values:      "{set: {token: '{{ env '}}",
+			wantLoadErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := Parse([]byte("routes:\n  - path: /jenkins\n    upstream:\n      query: " + tt.values + "\n"))
+			if (err != nil) != tt.wantLoadErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantLoadErr)
+			}
+			if tt.wantLoadErr {
+				return
+			}
+
+			gotSet, gotAdd, err := config.Routes[0].Upstream.Query.Render()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Values.Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(gotSet, tt.wantSet) || !reflect.DeepEqual(gotAdd, tt.wantAdd) {
+				t.Errorf("Values.Render() = %v, %v, want %v, %v", gotSet, gotAdd, tt.wantSet, tt.wantAdd)
+			}
+		})
+	}
+}
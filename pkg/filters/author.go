@@ -0,0 +1,48 @@
+package filters
+
+import (
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+const (
+	DraftFilterName = "draft"
+	BotFilterName   = "bot"
+)
+
+// DraftFilter drops hooks about draft pull and merge requests. Marking a
+// pull request ready for review clears its draft flag, so that hook passes.
+type DraftFilter struct{}
+
+func NewDraftFilter() *DraftFilter {
+	return &DraftFilter{}
+}
+
+func (f *DraftFilter) Name() string {
+	return DraftFilterName
+}
+
+func (f *DraftFilter) Allow(provider providers.Provider, hook providers.Hook) (bool, string) {
+	if provider.IsDraft(hook) {
+		return false, "Pull request is a draft"
+	}
+	return true, ""
+}
+
+// BotFilter drops hooks sent on behalf of bots, such as dependabot[bot] or
+// the bot users of Gitlab access tokens
+type BotFilter struct{}
+
+func NewBotFilter() *BotFilter {
+	return &BotFilter{}
+}
+
+func (f *BotFilter) Name() string {
+	return BotFilterName
+}
+
+func (f *BotFilter) Allow(provider providers.Provider, hook providers.Hook) (bool, string) {
+	if provider.IsBot(hook) {
+		return false, "Sender is a bot"
+	}
+	return true, ""
+}
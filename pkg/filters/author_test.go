@@ -0,0 +1,143 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestDraftFilter_Allow(t *testing.T) {
+	githubProvider, _ := providers.NewGithubProvider("")
+	gitlabProvider, _ := providers.NewGitlabProvider("")
+
+	type args struct {
+		provider providers.Provider
+		hook     providers.Hook
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantAllow  bool
+		wantReason string
+	}{
+		{
+			name: "TestAllowWithGithubDraftPullRequest",
+			args: args{
+				provider: githubProvider,
+				hook: createGithubHook(string(providers.GithubPullRequestEvent),
+					`{"action": "synchronize", "pull_request": {"draft": true}}`),
+			},
+			wantReason: "Pull request is a draft",
+		},
+		{
+			name: "TestAllowWithGithubReadyForReview",
+			args: args{
+				provider: githubProvider,
+				hook: createGithubHook(string(providers.GithubPullRequestEvent),
+					`{"action": "ready_for_review", "pull_request": {"draft": false}}`),
+			},
+			wantAllow: true,
+		},
+		{
+			name: "TestAllowWithGitlabWorkInProgressMergeRequest",
+			args: args{
+				provider: gitlabProvider,
+				hook: createGitlabHook(string(providers.GitlabMergeRequestEvent),
+					`{"object_attributes": {"work_in_progress": true}}`),
+			},
+			wantReason: "Pull request is a draft",
+		},
+		{
+			name: "TestAllowWithGitlabPush",
+			args: args{
+				provider: gitlabProvider,
+				hook:     createGitlabHook(string(providers.GitlabPushEvent), `{"object_attributes": {"draft": true}}`),
+			},
+			wantAllow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAllow, gotReason := NewDraftFilter().Allow(tt.args.provider, tt.args.hook)
+			if gotAllow != tt.wantAllow || gotReason != tt.wantReason {
+				t.Errorf("DraftFilter.Allow() = %v, %q, want %v, %q", gotAllow, gotReason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestBotFilter_Allow(t *testing.T) {
+	githubProvider, _ := providers.NewGithubProvider("")
+	gitlabProvider, _ := providers.NewGitlabProvider("")
+
+	type args struct {
+		provider providers.Provider
+		hook     providers.Hook
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantAllow  bool
+		wantReason string
+	}{
+		{
+			name: "TestAllowWithGithubBotType",
+			args: args{
+				provider: githubProvider,
+				hook: createGithubHook(string(providers.GithubPullRequestEvent),
+					`{"sender": {"login": "renovate", "type": "Bot"}}`),
+			},
+			wantReason: "Sender is a bot",
+		},
+		{
+			name: "TestAllowWithGithubBotSuffix",
+			args: args{
+				provider: githubProvider,
+				hook: createGithubHook(string(providers.GithubPushEvent),
+					`{"sender": {"login": "dependabot[bot]", "type": "User"}}`),
+			},
+			wantReason: "Sender is a bot",
+		},
+		{
+			name: "TestAllowWithGithubUser",
+			args: args{
+				provider: githubProvider,
+				hook: createGithubHook(string(providers.GithubPushEvent),
+					`{"sender": {"login": "octocat", "type": "User"}}`),
+			},
+			wantAllow: true,
+		},
+		{
+			name: "TestAllowWithGitlabProjectBotPush",
+			args: args{
+				provider: gitlabProvider,
+				hook:     createGitlabHook(string(providers.GitlabPushEvent), `{"user_username": "project_42_bot_3f2a9c"}`),
+			},
+			wantReason: "Sender is a bot",
+		},
+		{
+			name: "TestAllowWithGitlabGroupBotMergeRequest",
+			args: args{
+				provider: gitlabProvider,
+				hook:     createGitlabHook(string(providers.GitlabMergeRequestEvent), `{"user": {"username": "group_7_bot"}}`),
+			},
+			wantReason: "Sender is a bot",
+		},
+		{
+			name: "TestAllowWithGitlabUser",
+			args: args{
+				provider: gitlabProvider,
+				hook:     createGitlabHook(string(providers.GitlabMergeRequestEvent), `{"user": {"username": "project_lead"}}`),
+			},
+			wantAllow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAllow, gotReason := NewBotFilter().Allow(tt.args.provider, tt.args.hook)
+			if gotAllow != tt.wantAllow || gotReason != tt.wantReason {
+				t.Errorf("BotFilter.Allow() = %v, %q, want %v, %q", gotAllow, gotReason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
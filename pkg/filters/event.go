@@ -0,0 +1,115 @@
+package filters
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+const EventFilterName = "event"
+
+// EventFilter forwards hooks whose event and action are allowed and not denied.
+// Rules are written "event", "event:action" or "event:{action1,action2}", e.g.
+// "push" or "pull_request:{opened,synchronize,reopened}". A rule without
+// actions matches every action of the event. Names are compared ignoring case.
+type EventFilter struct {
+	allow []eventRule
+	deny  []eventRule
+}
+
+type eventRule struct {
+	event   string
+	actions []string
+}
+
+func NewEventFilter(allow []string, deny []string) (*EventFilter, error) {
+	allowRules, err := parseEventRules(allow)
+	if err != nil {
+		return nil, err
+	}
+	denyRules, err := parseEventRules(deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventFilter{
+		allow: allowRules,
+		deny:  denyRules,
+	}, nil
+}
+
+func parseEventRules(rules []string) ([]eventRule, error) {
+	parsed := []eventRule{}
+	for _, rule := range rules {
+		event, actions, hasActions := strings.Cut(strings.TrimSpace(rule), ":")
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == "" {
+			return nil, errors.New("Invalid event rule '" + rule + "': event cannot be empty")
+		}
+
+		parsedRule := eventRule{event: event}
+		if hasActions {
+			actions = strings.TrimSpace(actions)
+			if strings.HasPrefix(actions, "{") && strings.HasSuffix(actions, "}") {
+				actions = actions[1 : len(actions)-1]
+			}
+			for _, action := range strings.Split(actions, ",") {
+				if action = strings.ToLower(strings.TrimSpace(action)); action != "" {
+					parsedRule.actions = append(parsedRule.actions, action)
+				}
+			}
+			if len(parsedRule.actions) == 0 {
+				return nil, errors.New("Invalid event rule '" + rule + "': actions cannot be empty")
+			}
+		}
+		parsed = append(parsed, parsedRule)
+	}
+	return parsed, nil
+}
+
+func (r eventRule) match(event string, action string) bool {
+	if r.event != event {
+		return false
+	}
+	if len(r.actions) == 0 {
+		return true
+	}
+	for _, ruleAction := range r.actions {
+		if ruleAction == action {
+			return true
+		}
+	}
+	return false
+}
+
+func matchEventRules(rules []eventRule, event string, action string) bool {
+	for _, rule := range rules {
+		if rule.match(event, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *EventFilter) Name() string {
+	return EventFilterName
+}
+
+func (f *EventFilter) Allow(provider providers.Provider, hook providers.Hook) (bool, string) {
+	event := strings.ToLower(string(provider.GetEventType(hook)))
+	action := strings.ToLower(provider.GetAction(hook))
+
+	description := event
+	if action != "" {
+		description += ":" + action
+	}
+
+	if len(f.allow) > 0 && !matchEventRules(f.allow, event, action) {
+		return false, "Event '" + description + "' is not allowed"
+	}
+	if matchEventRules(f.deny, event, action) {
+		return false, "Event '" + description + "' is denied"
+	}
+	return true, ""
+}
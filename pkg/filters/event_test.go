@@ -0,0 +1,104 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func createGithubHook(event string, payload string) providers.Hook {
+	return providers.Hook{
+		Headers: map[string]string{
+			providers.XGitHubEvent: event,
+		},
+		Payload: []byte(payload),
+	}
+}
+
+func TestEventFilter_Allow(t *testing.T) {
+	provider, _ := providers.NewGithubProvider("")
+	pushHook := createGithubHook(string(providers.GithubPushEvent), `{"ref": "refs/heads/main"}`)
+	openedHook := createGithubHook(string(providers.GithubPullRequestEvent), `{"action": "opened"}`)
+	labeledHook := createGithubHook(string(providers.GithubPullRequestEvent), `{"action": "labeled"}`)
+	commentHook := createGithubHook(string(providers.GithubIssueCommentEvent), `{"action": "created"}`)
+
+	allow := []string{"pull_request:{opened,synchronize,reopened}", "push", "issue_comment:created"}
+	type args struct {
+		allow []string
+		deny  []string
+		hook  providers.Hook
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantAllow  bool
+		wantReason string
+	}{
+		{
+			name:      "TestAllowWithAllowedEvent",
+			args:      args{allow: allow, hook: pushHook},
+			wantAllow: true,
+		},
+		{
+			name:      "TestAllowWithAllowedAction",
+			args:      args{allow: allow, hook: openedHook},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithNotAllowedAction",
+			args:       args{allow: allow, hook: labeledHook},
+			wantReason: "Event 'pull_request:labeled' is not allowed",
+		},
+		{
+			name:      "TestAllowWithSingleAllowedAction",
+			args:      args{allow: allow, hook: commentHook},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithDeniedAction",
+			args:       args{deny: []string{"pull_request:labeled"}, hook: labeledHook},
+			wantReason: "Event 'pull_request:labeled' is denied",
+		},
+		{
+			name:       "TestAllowWithDeniedEvent",
+			args:       args{deny: []string{"Push"}, hook: pushHook},
+			wantReason: "Event 'push' is denied",
+		},
+		{
+			name:      "TestAllowWithoutRules",
+			args:      args{hook: labeledHook},
+			wantAllow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewEventFilter(tt.args.allow, tt.args.deny)
+			if err != nil {
+				t.Fatalf("NewEventFilter() error = %v", err)
+			}
+			gotAllow, gotReason := filter.Allow(provider, tt.args.hook)
+			if gotAllow != tt.wantAllow || gotReason != tt.wantReason {
+				t.Errorf("EventFilter.Allow() = %v, %q, want %v, %q", gotAllow, gotReason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestNewEventFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		wantErr bool
+	}{
+		{name: "TestNewEventFilterWithValidRules", allow: []string{"push", "pull_request:{opened, reopened}"}},
+		{name: "TestNewEventFilterWithEmptyEvent", allow: []string{":opened"}, wantErr: true},
+		{name: "TestNewEventFilterWithEmptyActions", allow: []string{"pull_request:{}"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEventFilter(tt.allow, nil); (err != nil) != tt.wantErr {
+				t.Errorf("NewEventFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package filters
+
+import (
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+	"github.com/stakater/GitWebhookProxy/pkg/utils"
+)
+
+const ChangedFilesFilterName = "changedFiles"
+
+// ChangedFilesFilter forwards pushes changing at least one file that is
+// included and not excluded, e.g. "services/billing/**". Hooks without file
+// lists, such as pull requests or tag pushes, and pushes whose file list is
+// empty or truncated are always forwarded.
+type ChangedFilesFilter struct {
+	include []*utils.Pattern
+	exclude []*utils.Pattern
+}
+
+func NewChangedFilesFilter(include []string, exclude []string) (*ChangedFilesFilter, error) {
+	includePatterns, err := utils.CompilePatterns(include)
+	if err != nil {
+		return nil, err
+	}
+	excludePatterns, err := utils.CompilePatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangedFilesFilter{
+		include: includePatterns,
+		exclude: excludePatterns,
+	}, nil
+}
+
+func (f *ChangedFilesFilter) Name() string {
+	return ChangedFilesFilterName
+}
+
+func (f *ChangedFilesFilter) Allow(provider providers.Provider, hook providers.Hook) (bool, string) {
+	files, ok := provider.GetChangedFiles(hook)
+	if !ok {
+		return true, ""
+	}
+
+	for _, file := range files {
+		if len(f.include) > 0 && !utils.MatchAny(f.include, file) {
+			continue
+		}
+		if utils.MatchAny(f.exclude, file) {
+			continue
+		}
+		return true, ""
+	}
+	return false, "No changed file matches the route"
+}
@@ -0,0 +1,77 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestChangedFilesFilter_Allow(t *testing.T) {
+	provider, _ := providers.NewGitlabProvider("")
+	pushHook := createGitlabHook(string(providers.GitlabPushEvent),
+		`{"commits": [{"added": ["services/billing/api/main.go"]}, {"modified": ["docs/billing.md"]}]}`)
+	emptyPushHook := createGitlabHook(string(providers.GitlabPushEvent), `{"commits": []}`)
+	truncatedPushHook := createGitlabHook(string(providers.GitlabPushEvent),
+		`{"commits": [{"modified": ["docs/billing.md"]}], "total_commits_count": 21}`)
+	mergeRequestHook := createGitlabHook(string(providers.GitlabMergeRequestEvent), `{}`)
+
+	type args struct {
+		include []string
+		exclude []string
+		hook    providers.Hook
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantAllow  bool
+		wantReason string
+	}{
+		{
+			name:      "TestAllowWithIncludedFile",
+			args:      args{include: []string{"services/billing/**"}, hook: pushHook},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithNotIncludedFiles",
+			args:       args{include: []string{"services/shipping/**"}, hook: pushHook},
+			wantReason: "No changed file matches the route",
+		},
+		{
+			name:      "TestAllowWithSomeFilesExcluded",
+			args:      args{exclude: []string{"**/*.md"}, hook: pushHook},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithAllFilesExcluded",
+			args:       args{include: []string{"docs/**"}, exclude: []string{"**/*.md"}, hook: pushHook},
+			wantReason: "No changed file matches the route",
+		},
+		{
+			name:      "TestAllowWithoutChangedFiles",
+			args:      args{include: []string{"services/billing/**"}, hook: emptyPushHook},
+			wantAllow: true,
+		},
+		{
+			name:      "TestAllowWithTruncatedChangedFiles",
+			args:      args{include: []string{"services/billing/**"}, hook: truncatedPushHook},
+			wantAllow: true,
+		},
+		{
+			name:      "TestAllowWithoutFileLists",
+			args:      args{include: []string{"services/billing/**"}, hook: mergeRequestHook},
+			wantAllow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewChangedFilesFilter(tt.args.include, tt.args.exclude)
+			if err != nil {
+				t.Fatalf("NewChangedFilesFilter() error = %v", err)
+			}
+			gotAllow, gotReason := filter.Allow(provider, tt.args.hook)
+			if gotAllow != tt.wantAllow || gotReason != tt.wantReason {
+				t.Errorf("ChangedFilesFilter.Allow() = %v, %q, want %v, %q", gotAllow, gotReason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package filters
+
+import (
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+// Filter decides whether a validated hook is forwarded to the upstream
+type Filter interface {
+	// Name identifies the filter in logs and responses
+	Name() string
+	// Allow returns false and the reason when the hook must not be forwarded
+	Allow(provider providers.Provider, hook providers.Hook) (bool, string)
+}
+
+// Apply runs every filter in order and returns the first one dropping the
+// hook with its reason, or nil when the hook is allowed
+func Apply(filters []Filter, provider providers.Provider, hook providers.Hook) (Filter, string) {
+	for _, filter := range filters {
+		if allowed, reason := filter.Allow(provider, hook); !allowed {
+			return filter, reason
+		}
+	}
+	return nil, ""
+}
@@ -0,0 +1,81 @@
+package filters
+
+import (
+	"strings"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+	"github.com/stakater/GitWebhookProxy/pkg/utils"
+)
+
+const (
+	RefFilterName     = "ref"
+	HeadRefFilterName = "headRef"
+)
+
+// RefFilter forwards hooks whose git reference is included and not excluded.
+// Patterns match either the full reference or the branch or tag name, e.g.
+// "main", "release/*" or "refs/tags/v*". Hooks without a reference, such as
+// comments, are always forwarded.
+type RefFilter struct {
+	include []*utils.Pattern
+	exclude []*utils.Pattern
+	head    bool
+}
+
+// NewRefFilter filters on the pushed ref, or the base branch of pull requests
+func NewRefFilter(include []string, exclude []string) (*RefFilter, error) {
+	return newRefFilter(include, exclude, false)
+}
+
+// NewHeadRefFilter filters on the head branch of pull requests
+func NewHeadRefFilter(include []string, exclude []string) (*RefFilter, error) {
+	return newRefFilter(include, exclude, true)
+}
+
+func newRefFilter(include []string, exclude []string, head bool) (*RefFilter, error) {
+	includePatterns, err := utils.CompilePatterns(include)
+	if err != nil {
+		return nil, err
+	}
+	excludePatterns, err := utils.CompilePatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefFilter{
+		include: includePatterns,
+		exclude: excludePatterns,
+		head:    head,
+	}, nil
+}
+
+func (f *RefFilter) Name() string {
+	if f.head {
+		return HeadRefFilterName
+	}
+	return RefFilterName
+}
+
+func (f *RefFilter) Allow(provider providers.Provider, hook providers.Hook) (bool, string) {
+	ref := provider.GetRef(hook)
+	if f.head {
+		ref = provider.GetHeadRef(hook)
+	}
+	if ref == "" {
+		return true, ""
+	}
+
+	if len(f.include) > 0 && !matchRef(f.include, ref) {
+		return false, "Ref '" + ref + "' is not included"
+	}
+	if matchRef(f.exclude, ref) {
+		return false, "Ref '" + ref + "' is excluded"
+	}
+	return true, ""
+}
+
+// matchRef matches both the full reference and the branch or tag name
+func matchRef(patterns []*utils.Pattern, ref string) bool {
+	shortRef := strings.TrimPrefix(strings.TrimPrefix(ref, providers.BranchRefPrefix), providers.TagRefPrefix)
+	return utils.MatchAny(patterns, ref) || utils.MatchAny(patterns, shortRef)
+}
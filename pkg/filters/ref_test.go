@@ -0,0 +1,96 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func createGitlabHook(event string, payload string) providers.Hook {
+	return providers.Hook{
+		Headers: map[string]string{
+			providers.XGitlabEvent: event,
+		},
+		Payload: []byte(payload),
+	}
+}
+
+func TestRefFilter_Allow(t *testing.T) {
+	provider, _ := providers.NewGitlabProvider("")
+	pushHook := createGitlabHook(string(providers.GitlabPushEvent), `{"ref": "refs/heads/release/1.0"}`)
+	tagHook := createGitlabHook(string(providers.GitlabTagPushEvent), `{"ref": "refs/tags/v1.2"}`)
+	mergeRequestHook := createGitlabHook(string(providers.GitlabMergeRequestEvent),
+		`{"object_attributes": {"source_branch": "feature/login", "target_branch": "main"}}`)
+	commentHook := createGitlabHook("Note Hook", `{}`)
+
+	type args struct {
+		include []string
+		exclude []string
+		head    bool
+		hook    providers.Hook
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantAllow  bool
+		wantReason string
+	}{
+		{
+			name:      "TestAllowWithIncludedBranch",
+			args:      args{include: []string{"main", "release/*"}, hook: pushHook},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithNotIncludedBranch",
+			args:       args{include: []string{"main"}, hook: pushHook},
+			wantReason: "Ref 'refs/heads/release/1.0' is not included",
+		},
+		{
+			name:       "TestAllowWithExcludedBranch",
+			args:       args{exclude: []string{"release/**"}, hook: pushHook},
+			wantReason: "Ref 'refs/heads/release/1.0' is excluded",
+		},
+		{
+			name:      "TestAllowWithIncludedFullTagRef",
+			args:      args{include: []string{"refs/tags/v*"}, hook: tagHook},
+			wantAllow: true,
+		},
+		{
+			name:      "TestAllowWithIncludedTagRegex",
+			args:      args{include: []string{"regex:^v[0-9]+\\.[0-9]+$"}, hook: tagHook},
+			wantAllow: true,
+		},
+		{
+			name:      "TestAllowWithMergeRequestTargetBranch",
+			args:      args{include: []string{"main"}, hook: mergeRequestHook},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithMergeRequestExcludedSourceBranch",
+			args:       args{exclude: []string{"feature/*"}, head: true, hook: mergeRequestHook},
+			wantReason: "Ref 'refs/heads/feature/login' is excluded",
+		},
+		{
+			name:      "TestAllowWithHeadRefOnPush",
+			args:      args{include: []string{"feature/*"}, head: true, hook: pushHook},
+			wantAllow: true,
+		},
+		{
+			name:      "TestAllowWithoutRef",
+			args:      args{include: []string{"main"}, hook: commentHook},
+			wantAllow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newRefFilter(tt.args.include, tt.args.exclude, tt.args.head)
+			if err != nil {
+				t.Fatalf("newRefFilter() error = %v", err)
+			}
+			gotAllow, gotReason := filter.Allow(provider, tt.args.hook)
+			if gotAllow != tt.wantAllow || gotReason != tt.wantReason {
+				t.Errorf("RefFilter.Allow() = %v, %q, want %v, %q", gotAllow, gotReason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
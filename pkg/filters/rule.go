@@ -0,0 +1,127 @@
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+const RuleFilterName = "rule"
+
+// RuleFilter forwards hooks matching one of the allow expressions, if any,
+// and none of the deny expressions. Expressions use the expr language
+// (https://expr-lang.org) and see the variables of ruleEnv. An expression
+// failing at runtime, e.g. on a missing payload field, does not match.
+type RuleFilter struct {
+	allow []*rule
+	deny  []*rule
+}
+
+type rule struct {
+	source  string
+	program *vm.Program
+}
+
+// ruleEnv lists the variables available to expressions with their types
+func ruleEnv() map[string]any {
+	return map[string]any{
+		"payload":    map[string]any{},
+		"headers":    map[string]string{},
+		"provider":   "",
+		"event":      "",
+		"action":     "",
+		"committer":  "",
+		"repository": "",
+		"ref":        "",
+		"headRef":    "",
+	}
+}
+
+func NewRuleFilter(allow []string, deny []string) (*RuleFilter, error) {
+	allowRules, err := compileRules(allow)
+	if err != nil {
+		return nil, err
+	}
+	denyRules, err := compileRules(deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RuleFilter{
+		allow: allowRules,
+		deny:  denyRules,
+	}, nil
+}
+
+func compileRules(sources []string) ([]*rule, error) {
+	rules := []*rule{}
+	for _, source := range sources {
+		program, err := expr.Compile(source, expr.Env(ruleEnv()), expr.AsBool())
+		if err != nil {
+			return nil, fmt.Errorf("Invalid rule '%s': %s", source, err)
+		}
+		rules = append(rules, &rule{source: source, program: program})
+	}
+	return rules, nil
+}
+
+func (r *rule) match(env map[string]any) bool {
+	matched, err := expr.Run(r.program, env)
+	if err != nil {
+		return false
+	}
+	return matched.(bool)
+}
+
+func (f *RuleFilter) Name() string {
+	return RuleFilterName
+}
+
+func (f *RuleFilter) Allow(provider providers.Provider, hook providers.Hook) (bool, string) {
+	env := newRuleEnv(provider, hook)
+
+	if len(f.allow) > 0 {
+		allowed := false
+		for _, rule := range f.allow {
+			if rule.match(env) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, "No rule allows the hook"
+		}
+	}
+	for _, rule := range f.deny {
+		if rule.match(env) {
+			return false, "Rule '" + rule.source + "' denies the hook"
+		}
+	}
+	return true, ""
+}
+
+func newRuleEnv(provider providers.Provider, hook providers.Hook) map[string]any {
+	payload := map[string]any{}
+	// Payloads that are not JSON objects leave payload empty
+	json.Unmarshal(hook.Payload, &payload)
+
+	headers := hook.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	return map[string]any{
+		"payload":    payload,
+		"headers":    headers,
+		"provider":   provider.GetProviderName(),
+		"event":      string(provider.GetEventType(hook)),
+		"action":     provider.GetAction(hook),
+		"committer":  provider.GetCommitter(hook),
+		"repository": provider.GetRepository(hook),
+		"ref":        provider.GetRef(hook),
+		"headRef":    provider.GetHeadRef(hook),
+	}
+}
@@ -0,0 +1,96 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestRuleFilter_Allow(t *testing.T) {
+	provider, _ := providers.NewGithubProvider("")
+	retestHook := createGithubHook(string(providers.GithubIssueCommentEvent),
+		`{"action": "created", "comment": {"body": "/retest please", "author_association": "MEMBER"}}`)
+	outsiderHook := createGithubHook(string(providers.GithubIssueCommentEvent),
+		`{"action": "created", "comment": {"body": "/retest", "author_association": "NONE"}}`)
+	draftHook := createGithubHook(string(providers.GithubPullRequestEvent),
+		`{"action": "opened", "pull_request": {"draft": true, "labels": [{"name": "ci"}]}}`)
+	labeledHook := createGithubHook(string(providers.GithubPullRequestEvent),
+		`{"action": "opened", "pull_request": {"draft": false, "labels": [{"name": "ci"}]}}`)
+
+	retest := `event == "issue_comment" && payload.comment.body startsWith "/retest" && payload.comment.author_association in ["MEMBER", "OWNER"]`
+	labeled := `event == "pull_request" && !payload.pull_request.draft && "ci" in map(payload.pull_request.labels, .name)`
+	type args struct {
+		allow []string
+		deny  []string
+		hook  providers.Hook
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantAllow  bool
+		wantReason string
+	}{
+		{
+			name:      "TestAllowWithMatchingAllowRule",
+			args:      args{allow: []string{retest, labeled}, hook: retestHook},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithoutMatchingAllowRule",
+			args:       args{allow: []string{retest, labeled}, hook: outsiderHook},
+			wantReason: "No rule allows the hook",
+		},
+		{
+			name:       "TestAllowWithDraftPullRequest",
+			args:       args{allow: []string{retest, labeled}, hook: draftHook},
+			wantReason: "No rule allows the hook",
+		},
+		{
+			name:      "TestAllowWithLabeledPullRequest",
+			args:      args{allow: []string{retest, labeled}, hook: labeledHook},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithMatchingDenyRule",
+			args:       args{deny: []string{`headers["X-GitHub-Event"] == "pull_request" && payload.pull_request.draft`}, hook: draftHook},
+			wantReason: `Rule 'headers["X-GitHub-Event"] == "pull_request" && payload.pull_request.draft' denies the hook`,
+		},
+		{
+			name:      "TestAllowWithFailingDenyRule",
+			args:      args{deny: []string{`payload.missing.field == "x"`}, hook: draftHook},
+			wantAllow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewRuleFilter(tt.args.allow, tt.args.deny)
+			if err != nil {
+				t.Fatalf("NewRuleFilter() error = %v", err)
+			}
+			gotAllow, gotReason := filter.Allow(provider, tt.args.hook)
+			if gotAllow != tt.wantAllow || gotReason != tt.wantReason {
+				t.Errorf("RuleFilter.Allow() = %v, %q, want %v, %q", gotAllow, gotReason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestNewRuleFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		wantErr bool
+	}{
+		{name: "TestNewRuleFilterWithValidRule", allow: []string{`committer != "jenkins"`}},
+		{name: "TestNewRuleFilterWithSyntaxError", allow: []string{`committer ==`}, wantErr: true},
+		{name: "TestNewRuleFilterWithUnknownVariable", allow: []string{`author == "jenkins"`}, wantErr: true},
+		{name: "TestNewRuleFilterWithNonBooleanRule", allow: []string{`committer`}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRuleFilter(tt.allow, nil); (err != nil) != tt.wantErr {
+				t.Errorf("NewRuleFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package filters
+
+import (
+	"strings"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+const SkipCIFilterName = "skipCI"
+
+// DefaultSkipCITokens are the directives recognized when no tokens are configured
+var DefaultSkipCITokens = []string{"[skip ci]", "[ci skip]"}
+
+// SkipCIFilter drops pushes whose head commit message contains one of the
+// skip tokens, ignoring case
+type SkipCIFilter struct {
+	tokens []string
+}
+
+func NewSkipCIFilter(tokens []string) *SkipCIFilter {
+	if len(tokens) == 0 {
+		tokens = DefaultSkipCITokens
+	}
+
+	lowerTokens := []string{}
+	for _, token := range tokens {
+		if token = strings.ToLower(strings.TrimSpace(token)); token != "" {
+			lowerTokens = append(lowerTokens, token)
+		}
+	}
+	return &SkipCIFilter{tokens: lowerTokens}
+}
+
+func (f *SkipCIFilter) Name() string {
+	return SkipCIFilterName
+}
+
+func (f *SkipCIFilter) Allow(provider providers.Provider, hook providers.Hook) (bool, string) {
+	message := strings.ToLower(provider.GetHeadCommitMessage(hook))
+	for _, token := range f.tokens {
+		if strings.Contains(message, token) {
+			return false, "Head commit message contains '" + token + "'"
+		}
+	}
+	return true, ""
+}
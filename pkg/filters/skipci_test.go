@@ -0,0 +1,60 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestSkipCIFilter_Allow(t *testing.T) {
+	provider, _ := providers.NewGitlabProvider("")
+	createPushHook := func(message string) providers.Hook {
+		return createGitlabHook(string(providers.GitlabPushEvent),
+			`{"commits": [{"message": "Add billing [skip ci]"}, {"message": "`+message+`"}]}`)
+	}
+
+	type args struct {
+		tokens []string
+		hook   providers.Hook
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantAllow  bool
+		wantReason string
+	}{
+		{
+			name:       "TestAllowWithDefaultToken",
+			args:       args{hook: createPushHook("Fix typo [CI SKIP]")},
+			wantReason: "Head commit message contains '[ci skip]'",
+		},
+		{
+			name:      "TestAllowWithTokenOnlyInOlderCommit",
+			args:      args{hook: createPushHook("Fix typo")},
+			wantAllow: true,
+		},
+		{
+			name:       "TestAllowWithConfiguredToken",
+			args:       args{tokens: []string{"[no build]"}, hook: createPushHook("Fix typo [no build]")},
+			wantReason: "Head commit message contains '[no build]'",
+		},
+		{
+			name:      "TestAllowWithDefaultTokenNotConfigured",
+			args:      args{tokens: []string{"[no build]"}, hook: createPushHook("Fix typo [skip ci]")},
+			wantAllow: true,
+		},
+		{
+			name:      "TestAllowWithoutHeadCommit",
+			args:      args{hook: createGitlabHook(string(providers.GitlabMergeRequestEvent), `{}`)},
+			wantAllow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAllow, gotReason := NewSkipCIFilter(tt.args.tokens).Allow(provider, tt.args.hook)
+			if gotAllow != tt.wantAllow || gotReason != tt.wantReason {
+				t.Errorf("SkipCIFilter.Allow() = %v, %q, want %v, %q", gotAllow, gotReason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
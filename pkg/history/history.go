@@ -0,0 +1,231 @@
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DefaultQueryLimit = 50
+	MaxQueryLimit     = 500
+	// Maximum number of upstream response bytes kept per delivery
+	ResponseSnippetLength = 1024
+
+	redactedHeaderValue = "[REDACTED]"
+)
+
+var deliveriesBucket = []byte("deliveries")
+
+// ErrNotFound is returned when no delivery exists for an id
+var ErrNotFound = errors.New("Delivery not found")
+
+// Delivery describes a webhook received by the proxy and what happened to it
+type Delivery struct {
+	ID              uint64            `json:"id"`
+	DeliveryID      string            `json:"deliveryId"`
+	ReceivedAt      time.Time         `json:"receivedAt"`
+	Provider        string            `json:"provider"`
+	Event           string            `json:"event"`
+	Repository      string            `json:"repository"`
+	Committer       string            `json:"committer"`
+	Path            string            `json:"path"`
+	Headers         map[string]string `json:"headers"`
+	Payload         string            `json:"payload"`
+	Validated       bool              `json:"validated"`
+	Decision        string            `json:"decision"`
+	Reason          string            `json:"reason,omitempty"`
+	UpstreamStatus  int               `json:"upstreamStatus,omitempty"`
+	LatencyMillis   int64             `json:"latencyMs"`
+	ResponseSnippet string            `json:"responseSnippet,omitempty"`
+}
+
+// Query filters the deliveries returned by Store.List. Zero values match everything.
+type Query struct {
+	Repository     string
+	Event          string
+	Decision       string
+	UpstreamStatus int
+	Since          time.Time
+	Until          time.Time
+	Limit          int
+}
+
+func (q Query) matches(d *Delivery) bool {
+	if q.Repository != "" && q.Repository != d.Repository {
+		return false
+	}
+	if q.Event != "" && q.Event != d.Event {
+		return false
+	}
+	if q.Decision != "" && q.Decision != d.Decision {
+		return false
+	}
+	if q.UpstreamStatus != 0 && q.UpstreamStatus != d.UpstreamStatus {
+		return false
+	}
+	if !q.Since.IsZero() && d.ReceivedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && d.ReceivedAt.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Store keeps the most recent deliveries in a bolt database file
+type Store struct {
+	db         *bolt.DB
+	maxEntries int
+
+	mutex sync.Mutex
+	count int
+}
+
+// Open opens or creates the delivery store at path, keeping at most maxEntries deliveries
+func Open(path string, maxEntries int) (*Store, error) {
+	if maxEntries <= 0 {
+		return nil, errors.New("Cannot create delivery store with non-positive maxEntries")
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		db:         db,
+		maxEntries: maxEntries,
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(deliveriesBucket)
+		if err != nil {
+			return err
+		}
+		s.count = bucket.Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close releases the database file
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record stores a delivery, assigning its ID and evicting the oldest
+// deliveries once the store is full. Sensitive headers are redacted.
+func (s *Store) Record(d *Delivery) error {
+	d.Headers = RedactHeaders(d.Headers)
+	if len(d.ResponseSnippet) > ResponseSnippetLength {
+		d.ResponseSnippet = d.ResponseSnippet[:ResponseSnippetLength]
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(deliveriesBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		d.ID = id
+
+		value, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(itob(id), value); err != nil {
+			return err
+		}
+		s.count++
+
+		// Keys are ordered by sequence, so the first ones are the oldest
+		cursor := bucket.Cursor()
+		for key, _ := cursor.First(); key != nil && s.count > s.maxEntries; key, _ = cursor.Next() {
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+			s.count--
+		}
+		return nil
+	})
+}
+
+// Get returns the delivery stored under id
+func (s *Store) Get(id uint64) (*Delivery, error) {
+	var d *Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(deliveriesBucket).Get(itob(id))
+		if value == nil {
+			return ErrNotFound
+		}
+		d = &Delivery{}
+		return json.Unmarshal(value, d)
+	})
+	return d, err
+}
+
+// List returns the deliveries matching q, newest first
+func (s *Store) List(q Query) ([]*Delivery, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	if limit > MaxQueryLimit {
+		limit = MaxQueryLimit
+	}
+
+	deliveries := []*Delivery{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(deliveriesBucket).Cursor()
+		for key, value := cursor.Last(); key != nil && len(deliveries) < limit; key, value = cursor.Prev() {
+			d := &Delivery{}
+			if err := json.Unmarshal(value, d); err != nil {
+				return err
+			}
+			if q.matches(d) {
+				deliveries = append(deliveries, d)
+			}
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+// RedactHeaders returns a copy of headers with secrets and signatures masked
+func RedactHeaders(headers map[string]string) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if logging.IsSensitiveKey(key) {
+			value = redactedHeaderValue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// ParseID converts the textual id used by the API into a store key
+func ParseID(id string) (uint64, error) {
+	return strconv.ParseUint(id, 10, 64)
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
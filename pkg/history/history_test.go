@@ -0,0 +1,121 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func openTestStore(t *testing.T, maxEntries int) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"), maxEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "history.db"), 0); err == nil {
+		t.Errorf("Open() with zero maxEntries did not return an error")
+	}
+}
+
+func TestStore_Record(t *testing.T) {
+	store := openTestStore(t, 2)
+
+	for _, repository := range []string{"repo1", "repo2", "repo3"} {
+		err := store.Record(&Delivery{
+			Repository: repository,
+			Headers: map[string]string{
+				providers.XGitlabToken: "secret",
+				providers.XGitlabEvent: "Push Hook",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deliveries, err := store.List(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("Store.List() returned %v deliveries, want %v", len(deliveries), 2)
+	}
+	if deliveries[0].Repository != "repo3" || deliveries[1].Repository != "repo2" {
+		t.Errorf("Store.List() = %v, %v, want newest deliveries first", deliveries[0].Repository, deliveries[1].Repository)
+	}
+	if deliveries[0].Headers[providers.XGitlabToken] != redactedHeaderValue {
+		t.Errorf("Store.Record() did not redact %v", providers.XGitlabToken)
+	}
+	if deliveries[0].Headers[providers.XGitlabEvent] != "Push Hook" {
+		t.Errorf("Store.Record() redacted %v", providers.XGitlabEvent)
+	}
+
+	if _, err := store.Get(deliveries[1].ID - 1); err != ErrNotFound {
+		t.Errorf("Store.Get() of evicted delivery error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestStore_List(t *testing.T) {
+	store := openTestStore(t, 10)
+	now := time.Now()
+
+	records := []*Delivery{
+		{Repository: "repo1", Event: "push", UpstreamStatus: 200, ReceivedAt: now.Add(-2 * time.Hour)},
+		{Repository: "repo1", Event: "pull_request", UpstreamStatus: 500, ReceivedAt: now.Add(-time.Hour)},
+		{Repository: "repo2", Event: "push", UpstreamStatus: 200, ReceivedAt: now},
+	}
+	for _, d := range records {
+		if err := store.Record(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{
+			name:  "TestListWithoutFilters",
+			query: Query{},
+			want:  3,
+		},
+		{
+			name:  "TestListByRepository",
+			query: Query{Repository: "repo1"},
+			want:  2,
+		},
+		{
+			name:  "TestListByEventAndStatus",
+			query: Query{Event: "push", UpstreamStatus: 200},
+			want:  2,
+		},
+		{
+			name:  "TestListByTimeRange",
+			query: Query{Since: now.Add(-90 * time.Minute), Until: now.Add(-30 * time.Minute)},
+			want:  1,
+		},
+		{
+			name:  "TestListWithLimit",
+			query: Query{Limit: 1},
+			want:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("Store.List() returned %v deliveries, want %v", len(got), tt.want)
+			}
+		})
+	}
+}
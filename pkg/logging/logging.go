@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// Structured log field names shared across the proxy
+const (
+	DeliveryIDKey     = "delivery_id"
+	ProviderKey       = "provider"
+	EventKey          = "event"
+	RepositoryKey     = "repo"
+	CommitterKey      = "committer"
+	DecisionKey       = "decision"
+	ReasonKey         = "reason"
+	UpstreamStatusKey = "upstream_status"
+	PathKey           = "path"
+	ValidatedWithKey  = "validated_with"
+	SourceKey         = "source"
+	ErrorKey          = "error"
+)
+
+// Decision values describing what the proxy did with a delivery
+const (
+	DecisionForwarded = "forwarded"
+	DecisionIgnored   = "ignored"
+	DecisionRejected  = "rejected"
+	DecisionFailed    = "failed"
+	DecisionDeferred  = "deferred"
+	DecisionCoalesced = "coalesced"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+
+	redactedValue = "[REDACTED]"
+)
+
+// sensitiveKeys lists substrings of attribute keys whose values must never be written
+var sensitiveKeys = []string{"secret", "signature", "token", "authorization", "password", "hash"}
+
+// ParseLevel converts a textual level (debug, info, warn, error) into a slog.Level
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return l, errors.New("Unknown log level '" + level + "' specified")
+	}
+	return l, nil
+}
+
+// New creates a structured logger writing to w in the given format and level.
+// Attributes with sensitive keys are always redacted.
+func New(w io.Writer, level string, format string) (*slog.Logger, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       l,
+		ReplaceAttr: redactAttr,
+	}
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatLogfmt:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, errors.New("Unknown log format '" + format + "' specified")
+	}
+}
+
+// Setup installs a structured logger as the process wide default, so that
+// both slog and the standard log package write through it
+func Setup(w io.Writer, level string, format string) error {
+	logger, err := New(w, level, format)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+	return nil
+}
+
+// IsSensitiveKey reports whether values stored under key must be redacted
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, redactedValue)
+	}
+	return a
+}
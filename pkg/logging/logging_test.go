@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	type args struct {
+		level  string
+		format string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "TestNewWithJSONFormat",
+			args: args{
+				level:  "info",
+				format: FormatJSON,
+			},
+		},
+		{
+			name: "TestNewWithLogfmtFormat",
+			args: args{
+				level:  "debug",
+				format: FormatLogfmt,
+			},
+		},
+		{
+			name: "TestNewWithInvalidLevel",
+			args: args{
+				level:  "loud",
+				format: FormatJSON,
+			},
+			wantErr: true,
+		},
+		{
+			name: "TestNewWithInvalidFormat",
+			args: args{
+				level:  "info",
+				format: "xml",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&bytes.Buffer{}, tt.args.level, tt.args.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew_Redaction(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := New(buf, "info", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("test", "secret", "s3cr3t", "X-Hub-Signature", "sha1=abc", DeliveryIDKey, "1234")
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "s3cr3t") || strings.Contains(buf.String(), "sha1=abc") {
+		t.Errorf("New() logged sensitive values: %s", buf.String())
+	}
+	if entry[DeliveryIDKey] != "1234" {
+		t.Errorf("New() %s = %v, want %v", DeliveryIDKey, entry[DeliveryIDKey], "1234")
+	}
+}
+
+func TestNew_Level(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := New(buf, "warn", FormatLogfmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("New() logged below configured level: %s", buf.String())
+	}
+}
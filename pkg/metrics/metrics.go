@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gwp"
+
+// Metric label names
+const (
+	ProviderLabel = "provider"
+	DecisionLabel = "decision"
+	FilterLabel   = "filter"
+	SecretLabel   = "secret"
+	KeyLabel      = "key"
+	ActionLabel   = "action"
+)
+
+var (
+	registry = newRegistry()
+	factory  = promauto.With(registry)
+
+	// Deliveries counts the webhooks received by the proxy by their outcome
+	Deliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deliveries_total",
+		Help:      "Webhook deliveries received, by provider and decision.",
+	}, []string{ProviderLabel, DecisionLabel})
+
+	// FilteredDeliveries counts the webhooks dropped by each route filter
+	FilteredDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "filtered_deliveries_total",
+		Help:      "Webhook deliveries dropped by route filters, by filter.",
+	}, []string{FilterLabel})
+
+	// ValidatedDeliveries counts the webhooks validated with each secret, so
+	// that previous secrets can be retired once they no longer match
+	ValidatedDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validated_deliveries_total",
+		Help:      "Webhook deliveries validated, by provider and matching secret.",
+	}, []string{ProviderLabel, SecretLabel})
+
+	// RateLimitedDeliveries counts the webhooks exceeding a route rate limit,
+	// by the key of the limit and whether they were rejected or delayed
+	RateLimitedDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_deliveries_total",
+		Help:      "Webhook deliveries exceeding route rate limits, by key and action.",
+	}, []string{KeyLabel, ActionLabel})
+)
+
+func newRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHandler(t *testing.T) {
+	// The registry is global, so only the increase of the counter is asserted
+	counter := FilteredDeliveries.WithLabelValues("skipCI")
+	before := testutil.ToFloat64(counter)
+	counter.Inc()
+	after := testutil.ToFloat64(counter)
+	if after-before != 1 {
+		t.Errorf("FilteredDeliveries increased by %v, want 1", after-before)
+	}
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, _ := ioutil.ReadAll(rr.Body)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler() status = %v, want %v", rr.Code, http.StatusOK)
+	}
+	want := `gwp_filtered_deliveries_total{filter="skipCI"} ` + strconv.FormatFloat(after, 'g', -1, 64)
+	if !strings.Contains(string(body), want) {
+		t.Errorf("Handler() body does not contain %q", want)
+	}
+}
@@ -0,0 +1,131 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ForwardedForHeader lists the addresses a request was forwarded for, the
+// last one being added by the nearest proxy
+const ForwardedForHeader = "X-Forwarded-For"
+
+// Allowlist matches addresses against a set of CIDR ranges
+type Allowlist struct {
+	prefixes []netip.Prefix
+}
+
+// ParseAllowlist parses CIDR ranges, or single addresses, into an Allowlist
+func ParseAllowlist(entries []string) (*Allowlist, error) {
+	allowlist := &Allowlist{}
+	if err := allowlist.add(entries); err != nil {
+		return nil, err
+	}
+	return allowlist, nil
+}
+
+func (a *Allowlist) add(entries []string) error {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return fmt.Errorf("Invalid CIDR range '%s': %s", entry, err)
+			}
+			a.prefixes = append(a.prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return fmt.Errorf("Invalid address '%s': %s", entry, err)
+		}
+		addr = addr.Unmap()
+		a.prefixes = append(a.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return nil
+}
+
+// Load adds the ranges of the file at path. Files may be a copy of the
+// GitHub meta API response, whose "hooks" ranges are used, or list a range
+// per line, ignoring empty lines and comments starting with '#'.
+func (a *Allowlist) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		meta := struct {
+			Hooks []string `json:"hooks"`
+		}{}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return fmt.Errorf("Invalid meta file '%s': %s", path, err)
+		}
+		if len(meta.Hooks) == 0 {
+			return fmt.Errorf("Meta file '%s' does not list any hooks range", path)
+		}
+		return a.add(meta.Hooks)
+	}
+
+	entries := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.SplitN(line, "#", 2)[0])
+		if len(line) > 0 {
+			entries = append(entries, line)
+		}
+	}
+	return a.add(entries)
+}
+
+// Empty reports whether the allowlist has no range
+func (a *Allowlist) Empty() bool {
+	return a == nil || len(a.prefixes) == 0
+}
+
+// Contains reports whether addr is in one of the ranges
+func (a *Allowlist) Contains(addr netip.Addr) bool {
+	if a == nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range a.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddr returns the address of the client that sent r. When the peer is
+// one of trustedProxies, the X-Forwarded-For header is walked from the nearest
+// proxy until an address that is not a trusted proxy, since only trusted
+// proxies can be relied upon to append the address they received from.
+func ClientAddr(r *http.Request, trustedProxies *Allowlist) (netip.Addr, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("Invalid remote address '%s'", r.RemoteAddr)
+	}
+	addr = addr.Unmap()
+
+	forwardedFor := []string{}
+	for _, header := range r.Header.Values(ForwardedForHeader) {
+		forwardedFor = append(forwardedFor, strings.Split(header, ",")...)
+	}
+	for i := len(forwardedFor) - 1; i >= 0 && trustedProxies.Contains(addr); i-- {
+		forwarded, err := netip.ParseAddr(strings.TrimSpace(forwardedFor[i]))
+		if err != nil {
+			return netip.Addr{}, errors.New("Invalid " + ForwardedForHeader + " header")
+		}
+		addr = forwarded.Unmap()
+	}
+	return addr, nil
+}
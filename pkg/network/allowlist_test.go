@@ -0,0 +1,157 @@
+package network
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"testing"
+)
+
+func createTestAllowlist(t *testing.T, entries ...string) *Allowlist {
+	allowlist, err := ParseAllowlist(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return allowlist
+}
+
+func TestAllowlist_Contains(t *testing.T) {
+	allowlist := createTestAllowlist(t, "140.82.112.0/20", "2606:50c0::/32", "10.1.2.3")
+	tests := []struct {
+		name string
+		addr string
+		want bool
+	}{
+		{name: "TestContainsWithAddressInRange", addr: "140.82.115.4", want: true},
+		{name: "TestContainsWithAddressOutOfRange", addr: "140.82.128.1", want: false},
+		{name: "TestContainsWithIPv6AddressInRange", addr: "2606:50c0:8000::154", want: true},
+		{name: "TestContainsWithMappedIPv4Address", addr: "::ffff:140.82.112.1", want: true},
+		{name: "TestContainsWithSingleAddress", addr: "10.1.2.3", want: true},
+		{name: "TestContainsWithNeighbourOfSingleAddress", addr: "10.1.2.4", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowlist.Contains(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("Allowlist.Contains(%v) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAllowlist(t *testing.T) {
+	if _, err := ParseAllowlist([]string{"140.82.112.0/33"}); err == nil {
+		t.Errorf("ParseAllowlist() with invalid range did not return an error")
+	}
+	if _, err := ParseAllowlist([]string{"github.com"}); err == nil {
+		t.Errorf("ParseAllowlist() with host name did not return an error")
+	}
+}
+
+func TestAllowlist_Load(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		contains string
+		wantErr  bool
+	}{
+		{
+			name:     "TestLoadWithGithubMeta",
+			content:  `{"verifiable_password_authentication": true, "hooks": ["192.30.252.0/22", "140.82.112.0/20"], "web": ["20.201.28.151/32"]}`,
+			contains: "140.82.112.10",
+		},
+		{
+			name:     "TestLoadWithRangePerLine",
+			content:  "# gitlab.example.com egress\n10.20.0.0/16\n\n10.30.0.1 # runner\n",
+			contains: "10.30.0.1",
+		},
+		{
+			name:    "TestLoadWithMetaWithoutHooks",
+			content: `{"web": ["20.201.28.151/32"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "TestLoadWithInvalidRange",
+			content: "10.20.0.0/16\nnot-a-range\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ranges")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			allowlist := &Allowlist{}
+			err := allowlist.Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Allowlist.Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !allowlist.Contains(netip.MustParseAddr(tt.contains)) {
+				t.Errorf("Allowlist.Load() did not add the range of %v", tt.contains)
+			}
+		})
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	trustedProxies := createTestAllowlist(t, "10.0.0.0/8")
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:       "TestClientAddrWithoutProxy",
+			remoteAddr: "140.82.115.4:41234",
+			want:       "140.82.115.4",
+		},
+		{
+			name:         "TestClientAddrWithUntrustedPeer",
+			remoteAddr:   "203.0.113.7:41234",
+			forwardedFor: []string{"140.82.115.4"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "TestClientAddrWithTrustedProxy",
+			remoteAddr:   "10.0.0.5:41234",
+			forwardedFor: []string{"140.82.115.4"},
+			want:         "140.82.115.4",
+		},
+		{
+			name:         "TestClientAddrWithSpoofedForwardedFor",
+			remoteAddr:   "10.0.0.5:41234",
+			forwardedFor: []string{"140.82.115.4, 203.0.113.7", "10.0.0.9"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "TestClientAddrWithInvalidForwardedFor",
+			remoteAddr:   "10.0.0.5:41234",
+			forwardedFor: []string{"unknown"},
+			wantErr:      true,
+		},
+		{
+			name:       "TestClientAddrWithIPv6Peer",
+			remoteAddr: "[2606:50c0:8000::154]:41234",
+			want:       "2606:50c0:8000::154",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/github-webhook/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, forwardedFor := range tt.forwardedFor {
+				r.Header.Add(ForwardedForHeader, forwardedFor)
+			}
+			got, err := ClientAddr(r, trustedProxies)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClientAddr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("ClientAddr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,9 +6,17 @@ import (
 	"net/http"
 
 	"github.com/stakater/GitWebhookProxy/pkg/providers"
+	"github.com/stakater/GitWebhookProxy/pkg/tracing"
 )
 
 func Parse(req *http.Request, provider providers.Provider) (*providers.Hook, error) {
+	_, span := tracing.Tracer().Start(req.Context(), "parser.Parse")
+	hook, err := parse(req, provider)
+	tracing.EndSpan(span, err)
+	return hook, err
+}
+
+func parse(req *http.Request, provider providers.Provider) (*providers.Hook, error) {
 	hook := &providers.Hook{
 		Headers: make(map[string]string),
 	}
@@ -21,6 +29,12 @@ func Parse(req *http.Request, provider providers.Provider) (*providers.Hook, err
 		return nil, errors.New("Required header '" + header + "' not found in Request")
 	}
 
+	for _, header := range provider.GetOptionalHeaderKeys() {
+		if req.Header.Get(header) != "" {
+			hook.Headers[header] = req.Header.Get(header)
+		}
+	}
+
 	if body, err := ioutil.ReadAll(req.Body); err != nil {
 		return nil, err
 	} else {
@@ -14,6 +14,7 @@ const (
 	parserGitlabTestSecret = "testSecret"
 	parserGitlabTestEvent  = "testEvent"
 	parserGitlabTestBody   = "testBody"
+	parserGitlabTestUUID   = "testUUID"
 )
 
 func createGitlabRequest(method string, path string, tokenHeader string,
@@ -25,6 +26,13 @@ func createGitlabRequest(method string, path string, tokenHeader string,
 	return req
 }
 
+func createGitlabRequestWithUUID(method string, path string, tokenHeader string,
+	eventHeader string, body string, uuid string) *http.Request {
+	req := createGitlabRequest(method, path, tokenHeader, eventHeader, body)
+	req.Header.Add(providers.XGitlabEventUUID, uuid)
+	return req
+}
+
 func createRequestWithWrongHeaders(method string, path string, tokenHeader string,
 	eventHeader string, body string) *http.Request {
 	req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
@@ -50,6 +58,13 @@ func createGitlabHook(tokenHeader string, tokenEvent string, body string, method
 	}
 }
 
+func createGitlabHookWithUUID(tokenHeader string, tokenEvent string, body string, method string,
+	uuid string) *providers.Hook {
+	hook := createGitlabHook(tokenHeader, tokenEvent, body, method)
+	hook.Headers[providers.XGitlabEventUUID] = uuid
+	return hook
+}
+
 func TestParse(t *testing.T) {
 	type args struct {
 		req      *http.Request
@@ -98,6 +113,16 @@ func TestParse(t *testing.T) {
 			},
 			want: createGitlabHook(parserGitlabTestSecret, parserGitlabTestEvent, "", http.MethodPost),
 		},
+		{
+			name: "TestParseWithOptionalHeader",
+			args: args{
+				req: createGitlabRequestWithUUID(http.MethodPost, "/dummy", parserGitlabTestSecret,
+					parserGitlabTestEvent, parserGitlabTestBody, parserGitlabTestUUID),
+				provider: createGitlabProvider(parserGitlabTestSecret),
+			},
+			want: createGitlabHookWithUUID(parserGitlabTestSecret, parserGitlabTestEvent,
+				parserGitlabTestBody, http.MethodPost, parserGitlabTestUUID),
+		},
 		{
 			name: "TestParseWithNoHeaders",
 			args: args{
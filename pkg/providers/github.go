@@ -3,10 +3,12 @@ package providers
 import (
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,26 +17,36 @@ const (
 	GithubIssueCommentEvent Event = "issue_comment"
 )
 
+// Markers of GitHub bot accounts
+const (
+	GithubBotType   = "Bot"
+	GithubBotSuffix = "[bot]"
+)
+
 // Header constants
 const (
-	XHubSignature   = "X-Hub-Signature"
-	XGitHubEvent    = "X-GitHub-Event"
-	XGitHubDelivery = "X-GitHub-Delivery"
+	XHubSignature    = "X-Hub-Signature"
+	XHubSignature256 = "X-Hub-Signature-256"
+	XGitHubEvent     = "X-GitHub-Event"
+	XGitHubDelivery  = "X-GitHub-Delivery"
 )
 
 const (
-	SignaturePrefix = "sha1="
-	SignatureLength = 45
-	GithubName      = "github"
+	SignaturePrefix    = "sha1="
+	SignatureLength    = 45
+	Signature256Prefix = "sha256="
+	GithubName         = "github"
 )
 
 type GithubProvider struct {
-	secret string
+	secret          string
+	previousSecrets []string
 }
 
-func NewGithubProvider(secret string) (*GithubProvider, error) {
+func NewGithubProvider(secret string, previousSecrets ...string) (*GithubProvider, error) {
 	return &GithubProvider{
-		secret: secret,
+		secret:          secret,
+		previousSecrets: previousSecrets,
 	}, nil
 }
 
@@ -55,17 +67,41 @@ func (p *GithubProvider) GetHeaderKeys() []string {
 	}
 }
 
+// Github sends no optional headers that need forwarding
+func (p *GithubProvider) GetOptionalHeaderKeys() []string {
+	return []string{}
+}
+
 // TODO: Update implementation and tests
 // Github Signature Validation:
 func (p *GithubProvider) Validate(hook Hook) bool {
+	_, ok := p.MatchSecret(hook)
+	return ok
+}
 
+// MatchSecret returns the index of the secret that signed hook, see SecretName
+func (p *GithubProvider) MatchSecret(hook Hook) (int, bool) {
 	githubSignature := hook.Headers[XHubSignature]
 	if len(githubSignature) != SignatureLength ||
 		!strings.HasPrefix(githubSignature, SignaturePrefix) {
-		return false
+		return 0, false
 	}
 
-	return IsValidPayload(p.secret, githubSignature[len(SignaturePrefix):], hook.Payload)
+	return matchSecret(p.secret, p.previousSecrets, func(secret string) bool {
+		return IsValidPayload(secret, githubSignature[len(SignaturePrefix):], hook.Payload)
+	})
+}
+
+// Sign sets the signature header of hook computed with the provider's secret,
+// or removes it when no secret is configured
+func (p *GithubProvider) Sign(hook *Hook) {
+	if len(strings.TrimSpace(p.secret)) == 0 {
+		delete(hook.Headers, XHubSignature)
+		delete(hook.Headers, XHubSignature256)
+		return
+	}
+	hook.Headers[XHubSignature] = SignaturePrefix + HashPayload(p.secret, hook.Payload)
+	hook.Headers[XHubSignature256] = Signature256Prefix + HashPayloadSHA256(p.secret, hook.Payload)
 }
 
 func (p *GithubProvider) GetProviderName() string {
@@ -78,37 +114,69 @@ func (p *GithubProvider) GetCommitter(hook Hook) string {
 	var pullRequestPayloadData GithubPullRequestPayload
 	var issueCommentPayloadData GithubIssueCommentPayload
 
-	log.Printf("Received event type: %v", eventType)
+	slog.Debug("Received event type", "event", eventType)
 	switch eventType {
 	case GithubPushEvent:
 		if err := json.Unmarshal(hook.Payload, &pushPayloadData); err != nil {
-			log.Printf("Github payload unmarshaling failed for Push event: %v", err)
+			slog.Warn("Github payload unmarshaling failed for Push event", "error", err)
 			return ""
 		}
 		return pushPayloadData.Sender.Login
 	case GithubPullRequestEvent:
 		if err := json.Unmarshal(hook.Payload, &pullRequestPayloadData); err != nil {
-			log.Printf("Github payload unmarshaling failed for Pull Request event: %v", err)
+			slog.Warn("Github payload unmarshaling failed for Pull Request event", "error", err)
 			return ""
 		}
 		return pullRequestPayloadData.Sender.Login
 	case GithubIssueCommentEvent:
 		if err := json.Unmarshal(hook.Payload, &issueCommentPayloadData); err != nil {
-			log.Printf("Github payload unmarshaling failed for issue comment event: %v", err)
+			slog.Warn("Github payload unmarshaling failed for issue comment event", "error", err)
 			return ""
 		}
 		return issueCommentPayloadData.Comment.User.Login
 	}
 
-	log.Printf("Event type is not supported: %v", eventType)
+	slog.Debug("Event type is not supported", "event", eventType)
 	return ""
 }
 
+func (p *GithubProvider) GetEventType(hook Hook) Event {
+	return Event(hook.Headers[XGitHubEvent])
+}
+
+// GetAction returns the action of events such as pull_request, e.g. "opened"
+func (p *GithubProvider) GetAction(hook Hook) string {
+	var payloadData struct {
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return ""
+	}
+	return payloadData.Action
+}
+
+func (p *GithubProvider) GetDeliveryID(hook Hook) string {
+	return hook.Headers[XGitHubDelivery]
+}
+
+// GetRepository returns the full name of the repository, which every
+// repository scoped Github event carries in the same place
+func (p *GithubProvider) GetRepository(hook Hook) string {
+	var payloadData struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return ""
+	}
+	return payloadData.Repository.FullName
+}
+
 // IsValidPayload checks if the github payload's hash fits with
 // the hash computed by GitHub sent as a header
 func IsValidPayload(secret, headerHash string, payload []byte) bool {
 	hash := HashPayload(secret, payload)
-	log.Printf("Calculated Hash: %s", hash)
 	return hmac.Equal(
 		[]byte(hash),
 		[]byte(headerHash),
@@ -124,3 +192,153 @@ func HashPayload(secret string, playloadBody []byte) string {
 	sum := hm.Sum(nil)
 	return fmt.Sprintf("%x", sum)
 }
+
+// HashPayloadSHA256 is the sha256 counterpart of HashPayload, sent by GitHub
+// in the X-Hub-Signature-256 header
+func HashPayloadSHA256(secret string, playloadBody []byte) string {
+	hm := hmac.New(sha256.New, []byte(secret))
+	hm.Write(playloadBody)
+	sum := hm.Sum(nil)
+	return fmt.Sprintf("%x", sum)
+}
+
+// GetRef returns the pushed ref of push events and the base branch of pull requests
+func (p *GithubProvider) GetRef(hook Hook) string {
+	switch p.GetEventType(hook) {
+	case GithubPushEvent:
+		var pushPayloadData GithubPushPayload
+		if err := json.Unmarshal(hook.Payload, &pushPayloadData); err != nil {
+			return ""
+		}
+		return pushPayloadData.Ref
+	case GithubPullRequestEvent:
+		var pullRequestPayloadData GithubPullRequestPayload
+		if err := json.Unmarshal(hook.Payload, &pullRequestPayloadData); err != nil {
+			return ""
+		}
+		return BranchRefPrefix + pullRequestPayloadData.PullRequest.Base.Ref
+	}
+	return ""
+}
+
+// IsPush tells whether the hook is about a push of a branch or a tag
+func (p *GithubProvider) IsPush(hook Hook) bool {
+	return p.GetEventType(hook) == GithubPushEvent
+}
+
+// GetHeadRef returns the head branch of pull requests
+func (p *GithubProvider) GetHeadRef(hook Hook) string {
+	if p.GetEventType(hook) != GithubPullRequestEvent {
+		return ""
+	}
+
+	var pullRequestPayloadData GithubPullRequestPayload
+	if err := json.Unmarshal(hook.Payload, &pullRequestPayloadData); err != nil {
+		return ""
+	}
+	return BranchRefPrefix + pullRequestPayloadData.PullRequest.Head.Ref
+}
+
+// GithubMaxPushCommits is the number of commits GitHub lists at most in a push
+// event; longer pushes are truncated.
+const GithubMaxPushCommits = 2048
+
+// GetChangedFiles returns the files added, modified or removed by the commits
+// of push events. It returns false for events without file lists, and for
+// pushes listing no commits or GithubMaxPushCommits commits, which may have
+// been truncated, as their changed files are unknown.
+func (p *GithubProvider) GetChangedFiles(hook Hook) ([]string, bool) {
+	if p.GetEventType(hook) != GithubPushEvent {
+		return nil, false
+	}
+
+	var pushPayloadData GithubPushPayload
+	if err := json.Unmarshal(hook.Payload, &pushPayloadData); err != nil {
+		return nil, false
+	}
+	if len(pushPayloadData.Commits) == 0 || len(pushPayloadData.Commits) >= GithubMaxPushCommits {
+		return nil, false
+	}
+
+	files, seen := []string{}, map[string]bool{}
+	for _, commit := range pushPayloadData.Commits {
+		files = appendChangedFiles(files, seen, commit.Added, commit.Modified, commit.Removed)
+	}
+	return files, true
+}
+
+// GetHeadCommitMessage returns the message of the head commit of push events
+func (p *GithubProvider) GetHeadCommitMessage(hook Hook) string {
+	if p.GetEventType(hook) != GithubPushEvent {
+		return ""
+	}
+
+	var pushPayloadData GithubPushPayload
+	if err := json.Unmarshal(hook.Payload, &pushPayloadData); err != nil {
+		return ""
+	}
+	return pushPayloadData.HeadCommit.Message
+}
+
+// IsDraft tells whether the hook is about a draft pull request
+func (p *GithubProvider) IsDraft(hook Hook) bool {
+	if p.GetEventType(hook) != GithubPullRequestEvent {
+		return false
+	}
+
+	var pullRequestPayloadData GithubPullRequestPayload
+	if err := json.Unmarshal(hook.Payload, &pullRequestPayloadData); err != nil {
+		return false
+	}
+	return pullRequestPayloadData.PullRequest.Draft
+}
+
+// IsBot tells whether the hook was sent on behalf of a bot, such as a GitHub
+// App like dependabot[bot]
+func (p *GithubProvider) IsBot(hook Hook) bool {
+	var payloadData struct {
+		Sender struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"sender"`
+	}
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return false
+	}
+	return payloadData.Sender.Type == GithubBotType || strings.HasSuffix(payloadData.Sender.Login, GithubBotSuffix)
+}
+
+// GetTimestamp returns when the event of the hook happened, as carried by the
+// signed payload: the push time of pushes, and the update time of pull
+// requests and comments
+func (p *GithubProvider) GetTimestamp(hook Hook) (time.Time, bool) {
+	var payloadData struct {
+		Repository struct {
+			PushedAt json.RawMessage `json:"pushed_at"`
+		} `json:"repository"`
+		PullRequest struct {
+			UpdatedAt time.Time `json:"updated_at"`
+		} `json:"pull_request"`
+		Comment struct {
+			UpdatedAt time.Time `json:"updated_at"`
+		} `json:"comment"`
+	}
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return time.Time{}, false
+	}
+
+	switch p.GetEventType(hook) {
+	case GithubPushEvent:
+		// Push payloads carry the push time as a unix timestamp
+		var pushedAt int64
+		if err := json.Unmarshal(payloadData.Repository.PushedAt, &pushedAt); err != nil || pushedAt == 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(pushedAt, 0), true
+	case GithubPullRequestEvent:
+		return payloadData.PullRequest.UpdatedAt, !payloadData.PullRequest.UpdatedAt.IsZero()
+	case GithubIssueCommentEvent:
+		return payloadData.Comment.UpdatedAt, !payloadData.Comment.UpdatedAt.IsZero()
+	}
+	return time.Time{}, false
+}
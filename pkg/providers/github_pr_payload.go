@@ -346,6 +346,7 @@ type GithubPullRequestPayload struct {
 		} `json:"_links"`
 		AuthorAssociation string `json:"author_association"`
 		Merged            bool   `json:"merged"`
+		Draft             bool   `json:"draft"`
 		Mergeable         bool   `json:"mergeable"`
 		Rebaseable        bool   `json:"rebaseable"`
 		MergeableState    string `json:"mergeable_state"`
@@ -2,7 +2,9 @@ package providers
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -246,3 +248,309 @@ func TestGithubProvider_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestGithubProvider_GetRepository(t *testing.T) {
+	type args struct {
+		hook Hook
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "TestGetRepositoryWithValidPayload",
+			args: args{
+				hook: Hook{
+					Payload: []byte(`{"repository":{"full_name":"stakater/GitWebhookProxy"}}`),
+				},
+			},
+			want: "stakater/GitWebhookProxy",
+		},
+		{
+			name: "TestGetRepositoryWithInvalidPayload",
+			args: args{
+				hook: Hook{
+					Payload: []byte(`invalid`),
+				},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GithubProvider{}
+			if got := p.GetRepository(tt.args.hook); got != tt.want {
+				t.Errorf("GithubProvider.GetRepository() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGithubProvider_MatchSecret(t *testing.T) {
+	payload := []byte(`{"ref":"refs/heads/master"}`)
+	tests := []struct {
+		name            string
+		previousSecrets []string
+		signedWith      string
+		wantIndex       int
+		wantOk          bool
+	}{
+		{
+			name:            "TestMatchSecretWithCurrentSecret",
+			previousSecrets: []string{"OldSecret"},
+			signedWith:      githubTestSecret,
+			wantIndex:       0,
+			wantOk:          true,
+		},
+		{
+			name:            "TestMatchSecretWithPreviousSecret",
+			previousSecrets: []string{"OldSecret"},
+			signedWith:      "OldSecret",
+			wantIndex:       1,
+			wantOk:          true,
+		},
+		{
+			name:            "TestMatchSecretWithUnknownSecret",
+			previousSecrets: []string{"OldSecret"},
+			signedWith:      "IncorrectSecret",
+			wantOk:          false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := NewGithubProvider(githubTestSecret, tt.previousSecrets...)
+			hook := Hook{
+				Headers: map[string]string{XHubSignature: SignaturePrefix + HashPayload(tt.signedWith, payload)},
+				Payload: payload,
+			}
+			gotIndex, gotOk := p.MatchSecret(hook)
+			if gotIndex != tt.wantIndex || gotOk != tt.wantOk {
+				t.Errorf("GithubProvider.MatchSecret() = %v %v, want %v %v", gotIndex, gotOk, tt.wantIndex, tt.wantOk)
+			}
+			if p.Validate(hook) != tt.wantOk {
+				t.Errorf("GithubProvider.Validate() = %v, want %v", !tt.wantOk, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGithubProvider_Sign(t *testing.T) {
+	type fields struct {
+		secret string
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		wantSignature bool
+	}{
+		{
+			name: "TestSignWithSecret",
+			fields: fields{
+				secret: githubTestSecret,
+			},
+			wantSignature: true,
+		},
+		{
+			name:          "TestSignWithoutSecret",
+			fields:        fields{},
+			wantSignature: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GithubProvider{
+				secret: tt.fields.secret,
+			}
+			hook := &Hook{
+				Headers: map[string]string{XHubSignature: "[REDACTED]", XHubSignature256: "[REDACTED]"},
+				Payload: []byte(`{"ref":"refs/heads/master"}`),
+			}
+			p.Sign(hook)
+
+			_, exists := hook.Headers[XHubSignature]
+			_, exists256 := hook.Headers[XHubSignature256]
+			if exists != tt.wantSignature || exists256 != tt.wantSignature {
+				t.Fatalf("GithubProvider.Sign() set signatures = %v %v, want %v", exists, exists256, tt.wantSignature)
+			}
+			if tt.wantSignature && !p.Validate(*hook) {
+				t.Errorf("GithubProvider.Sign() produced signature that does not validate")
+			}
+			if tt.wantSignature && hook.Headers[XHubSignature256] !=
+				Signature256Prefix+HashPayloadSHA256(tt.fields.secret, hook.Payload) {
+				t.Errorf("GithubProvider.Sign() produced sha256 signature %v", hook.Headers[XHubSignature256])
+			}
+		})
+	}
+}
+
+func TestHashPayloadSHA256(t *testing.T) {
+	// Example from https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+	want := "757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	if got := HashPayloadSHA256("It's a Secret to Everybody", []byte("Hello, World!")); got != want {
+		t.Errorf("HashPayloadSHA256() = %v, want %v", got, want)
+	}
+}
+
+func TestGithubProvider_GetChangedFiles(t *testing.T) {
+	type args struct {
+		hook Hook
+	}
+	tests := []struct {
+		name   string
+		args   args
+		want   []string
+		wantOk bool
+	}{
+		{
+			name: "TestGetChangedFilesWithPushEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitHubEvent: string(GithubPushEvent)},
+					Payload: []byte(`{"commits":[{"added":["a.go"],"modified":["b.go"]},{"modified":["b.go"],"removed":["c.go"]}]}`),
+				},
+			},
+			want:   []string{"a.go", "b.go", "c.go"},
+			wantOk: true,
+		},
+		{
+			name: "TestGetChangedFilesWithPushEventWithoutCommits",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitHubEvent: string(GithubPushEvent)},
+					Payload: []byte(`{"commits":[]}`),
+				},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+		{
+			name: "TestGetChangedFilesWithTruncatedPushEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitHubEvent: string(GithubPushEvent)},
+					Payload: []byte(`{"commits":[` + strings.Repeat(`{"added":["a.go"]},`, GithubMaxPushCommits-1) + `{"added":["a.go"]}]}`),
+				},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+		{
+			name: "TestGetChangedFilesWithPullRequestEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitHubEvent: string(GithubPullRequestEvent)},
+					Payload: []byte(`{}`),
+				},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GithubProvider{}
+			got, gotOk := p.GetChangedFiles(tt.args.hook)
+			if !reflect.DeepEqual(got, tt.want) || gotOk != tt.wantOk {
+				t.Errorf("GithubProvider.GetChangedFiles() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGithubProvider_GetHeadCommitMessage(t *testing.T) {
+	type args struct {
+		hook Hook
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "TestGetHeadCommitMessageWithPushEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitHubEvent: string(GithubPushEvent)},
+					Payload: []byte(`{"commits":[{"message":"first"}],"head_commit":{"message":"Update docs [skip ci]"}}`),
+				},
+			},
+			want: "Update docs [skip ci]",
+		},
+		{
+			name: "TestGetHeadCommitMessageWithPullRequestEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitHubEvent: string(GithubPullRequestEvent)},
+					Payload: []byte(`{"head_commit":{"message":"Update docs [skip ci]"}}`),
+				},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GithubProvider{}
+			if got := p.GetHeadCommitMessage(tt.args.hook); got != tt.want {
+				t.Errorf("GithubProvider.GetHeadCommitMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGithubProvider_GetTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   Event
+		payload string
+		want    time.Time
+		wantOk  bool
+	}{
+		{
+			name:    "TestGetTimestampWithPushEvent",
+			event:   GithubPushEvent,
+			payload: `{"repository":{"pushed_at":1528206305}}`,
+			want:    time.Unix(1528206305, 0),
+			wantOk:  true,
+		},
+		{
+			name:    "TestGetTimestampWithPullRequestEvent",
+			event:   GithubPullRequestEvent,
+			payload: `{"pull_request":{"updated_at":"2018-06-05T13:45:05Z"},"repository":{"pushed_at":"2018-06-05T13:40:00Z"}}`,
+			want:    time.Date(2018, 6, 5, 13, 45, 5, 0, time.UTC),
+			wantOk:  true,
+		},
+		{
+			name:    "TestGetTimestampWithIssueCommentEvent",
+			event:   GithubIssueCommentEvent,
+			payload: `{"comment":{"updated_at":"2018-06-05T13:45:05Z"}}`,
+			want:    time.Date(2018, 6, 5, 13, 45, 5, 0, time.UTC),
+			wantOk:  true,
+		},
+		{
+			name:    "TestGetTimestampWithoutTimestamp",
+			event:   GithubPushEvent,
+			payload: `{"ref":"refs/heads/master"}`,
+			wantOk:  false,
+		},
+		{
+			name:    "TestGetTimestampWithOtherEvent",
+			event:   "ping",
+			payload: `{"zen":"Keep it logically awesome."}`,
+			wantOk:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GithubProvider{}
+			hook := Hook{
+				Headers: map[string]string{XGitHubEvent: string(tt.event)},
+				Payload: []byte(tt.payload),
+			}
+			got, gotOk := p.GetTimestamp(hook)
+			if gotOk != tt.wantOk || !got.Equal(tt.want) {
+				t.Errorf("GithubProvider.GetTimestamp() = %v %v, want %v %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
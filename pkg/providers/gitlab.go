@@ -2,29 +2,39 @@ package providers
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // Header constants
 const (
 	XGitlabToken = "X-Gitlab-Token"
 	XGitlabEvent = "X-Gitlab-Event"
-	GitlabName   = "gitlab"
+	// Sent by Gitlab 15.x and later to identify a delivery
+	XGitlabEventUUID = "X-Gitlab-Event-UUID"
+	GitlabName       = "gitlab"
 )
 
 const (
 	GitlabPushEvent         Event = "Push Hook"
+	GitlabTagPushEvent      Event = "Tag Push Hook"
 	GitlabMergeRequestEvent Event = "Merge Request Hook"
 )
 
 type GitlabProvider struct {
-	secret string
+	secret          string
+	previousSecrets []string
 }
 
-func NewGitlabProvider(secret string) (*GitlabProvider, error) {
+// gitlabBotUsername matches the users created for project and group access tokens
+var gitlabBotUsername = regexp.MustCompile(`^(project|group)_\d+_bot(_[0-9a-f]+)?$`)
+
+func NewGitlabProvider(secret string, previousSecrets ...string) (*GitlabProvider, error) {
 	return &GitlabProvider{
-		secret: secret,
+		secret:          secret,
+		previousSecrets: previousSecrets,
 	}, nil
 }
 
@@ -48,22 +58,47 @@ func (p *GitlabProvider) GetHeaderKeys() []string {
 	}
 }
 
+// Headers sent by newer Gitlab versions only
+func (p *GitlabProvider) GetOptionalHeaderKeys() []string {
+	return []string{
+		XGitlabEventUUID,
+	}
+}
+
 // Gitlab token validation:
 // https://docs.gitlab.com/ee/user/project/integrations/webhooks.html#secret-token
 func (p *GitlabProvider) Validate(hook Hook) bool {
+	_, ok := p.MatchSecret(hook)
+	return ok
+}
+
+// MatchSecret returns the index of the secret sent as token of hook, see SecretName
+func (p *GitlabProvider) MatchSecret(hook Hook) (int, bool) {
 	token := hook.Headers[XGitlabToken]
 	// Validation fails if secret is configured but did not receive from gitlab
 	if len(token) <= 0 {
-		return false
+		return 0, false
 	}
 
-	return strings.TrimSpace(token) == strings.TrimSpace(p.secret)
+	return matchSecret(p.secret, p.previousSecrets, func(secret string) bool {
+		return strings.TrimSpace(token) == strings.TrimSpace(secret)
+	})
+}
+
+// Sign sets the token header of hook to the provider's secret,
+// or removes it when no secret is configured
+func (p *GitlabProvider) Sign(hook *Hook) {
+	if len(strings.TrimSpace(p.secret)) == 0 {
+		delete(hook.Headers, XGitlabToken)
+		return
+	}
+	hook.Headers[XGitlabToken] = p.secret
 }
 
 func (p *GitlabProvider) GetCommitter(hook Hook) string {
 	var payloadData GitlabPushPayload
 	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
-		log.Printf("Gitlab hook payload unmarshalling failed")
+		slog.Warn("Gitlab hook payload unmarshalling failed", "error", err)
 		return ""
 	}
 
@@ -74,3 +109,147 @@ func (p *GitlabProvider) GetCommitter(hook Hook) string {
 	}
 	return ""
 }
+
+func (p *GitlabProvider) GetEventType(hook Hook) Event {
+	return Event(hook.Headers[XGitlabEvent])
+}
+
+// GetAction returns the action of events such as merge requests, e.g. "open"
+func (p *GitlabProvider) GetAction(hook Hook) string {
+	var payloadData struct {
+		ObjectAttributes struct {
+			Action string `json:"action"`
+		} `json:"object_attributes"`
+	}
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return ""
+	}
+	return payloadData.ObjectAttributes.Action
+}
+
+func (p *GitlabProvider) GetDeliveryID(hook Hook) string {
+	return hook.Headers[XGitlabEventUUID]
+}
+
+// GetRepository returns the namespaced path of the project that
+// every project scoped Gitlab event carries
+func (p *GitlabProvider) GetRepository(hook Hook) string {
+	var payloadData struct {
+		Project struct {
+			NamespacePath string `json:"path_with_namespace"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return ""
+	}
+	return payloadData.Project.NamespacePath
+}
+
+// GetRef returns the pushed ref of push events and the target branch of merge requests
+func (p *GitlabProvider) GetRef(hook Hook) string {
+	switch p.GetEventType(hook) {
+	case GitlabPushEvent, GitlabTagPushEvent:
+		var payloadData GitlabPushPayload
+		if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+			return ""
+		}
+		return payloadData.Ref
+	case GitlabMergeRequestEvent:
+		var payloadData GitlabMergeRequestPayload
+		if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+			return ""
+		}
+		return BranchRefPrefix + payloadData.ObjectAttributes.TargetBranch
+	}
+	return ""
+}
+
+// IsPush tells whether the hook is about a push of a branch or a tag
+func (p *GitlabProvider) IsPush(hook Hook) bool {
+	eventType := p.GetEventType(hook)
+	return eventType == GitlabPushEvent || eventType == GitlabTagPushEvent
+}
+
+// GetHeadRef returns the source branch of merge requests
+func (p *GitlabProvider) GetHeadRef(hook Hook) string {
+	if p.GetEventType(hook) != GitlabMergeRequestEvent {
+		return ""
+	}
+
+	var payloadData GitlabMergeRequestPayload
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return ""
+	}
+	return BranchRefPrefix + payloadData.ObjectAttributes.SourceBranch
+}
+
+// GetChangedFiles returns the files added, modified or removed by the commits
+// of push events. It returns false for events without file lists, and for
+// pushes listing no commits or fewer than total_commits_count, whose changed
+// files are unknown.
+func (p *GitlabProvider) GetChangedFiles(hook Hook) ([]string, bool) {
+	if p.GetEventType(hook) != GitlabPushEvent {
+		return nil, false
+	}
+
+	var payloadData GitlabPushPayload
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return nil, false
+	}
+	if len(payloadData.Commits) == 0 || payloadData.TotalCommitsCount > int64(len(payloadData.Commits)) {
+		return nil, false
+	}
+
+	files, seen := []string{}, map[string]bool{}
+	for _, commit := range payloadData.Commits {
+		files = appendChangedFiles(files, seen, commit.CommitAdded, commit.CommitModified, commit.CommitRemoved)
+	}
+	return files, true
+}
+
+// GetHeadCommitMessage returns the message of the last commit of push events
+func (p *GitlabProvider) GetHeadCommitMessage(hook Hook) string {
+	if p.GetEventType(hook) != GitlabPushEvent {
+		return ""
+	}
+
+	var payloadData GitlabPushPayload
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil || len(payloadData.Commits) == 0 {
+		return ""
+	}
+	return payloadData.Commits[len(payloadData.Commits)-1].CommitMessage
+}
+
+// IsDraft tells whether the hook is about a draft merge request
+func (p *GitlabProvider) IsDraft(hook Hook) bool {
+	if p.GetEventType(hook) != GitlabMergeRequestEvent {
+		return false
+	}
+
+	var payloadData GitlabMergeRequestPayload
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return false
+	}
+	return payloadData.ObjectAttributes.Draft || payloadData.ObjectAttributes.WorkInProgress
+}
+
+// IsBot tells whether the hook was triggered by the bot user of a project or
+// group access token
+func (p *GitlabProvider) IsBot(hook Hook) bool {
+	var payloadData struct {
+		UserUsername string `json:"user_username"`
+		User         struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(hook.Payload, &payloadData); err != nil {
+		return false
+	}
+	return gitlabBotUsername.MatchString(payloadData.UserUsername) || gitlabBotUsername.MatchString(payloadData.User.Username)
+}
+
+// GetTimestamp always reports that the hook carries no timestamp, since Gitlab
+// does not send when an event was delivered and does not sign payloads
+func (p *GitlabProvider) GetTimestamp(hook Hook) (time.Time, bool) {
+	return time.Time{}, false
+}
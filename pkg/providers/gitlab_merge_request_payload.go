@@ -0,0 +1,37 @@
+package providers
+
+// GitlabMergeRequestPayload contains the information for Gitlab's merge request hook event
+type GitlabMergeRequestPayload struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		Name     string `json:"name"`
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	} `json:"user"`
+	Project struct {
+		ID            int64  `json:"id"`
+		Name          string `json:"name"`
+		WebURL        string `json:"web_url"`
+		NamespacePath string `json:"path_with_namespace"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		ID             int64  `json:"id"`
+		IID            int64  `json:"iid"`
+		Title          string `json:"title"`
+		Description    string `json:"description"`
+		State          string `json:"state"`
+		Action         string `json:"action"`
+		SourceBranch   string `json:"source_branch"`
+		TargetBranch   string `json:"target_branch"`
+		WorkInProgress bool   `json:"work_in_progress"`
+		Draft          bool   `json:"draft"`
+		URL            string `json:"url"`
+		LastCommit     struct {
+			ID        string `json:"id"`
+			Message   string `json:"message"`
+			Timestamp string `json:"timestamp"`
+			URL       string `json:"url"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+}
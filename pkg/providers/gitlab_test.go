@@ -246,3 +246,201 @@ func TestGitlabProvider_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestGitlabProvider_MatchSecret(t *testing.T) {
+	type fields struct {
+		secret          string
+		previousSecrets []string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		token     string
+		wantIndex int
+		wantOk    bool
+	}{
+		{
+			name:      "TestMatchSecretWithCurrentSecret",
+			fields:    fields{secret: gitlabTestSecret, previousSecrets: []string{"OldSecret"}},
+			token:     gitlabTestSecret,
+			wantIndex: 0,
+			wantOk:    true,
+		},
+		{
+			name:      "TestMatchSecretWithPreviousSecret",
+			fields:    fields{secret: gitlabTestSecret, previousSecrets: []string{"OldSecret", "OlderSecret"}},
+			token:     "OlderSecret",
+			wantIndex: 2,
+			wantOk:    true,
+		},
+		{
+			name:   "TestMatchSecretWithUnknownSecret",
+			fields: fields{secret: gitlabTestSecret, previousSecrets: []string{"OldSecret"}},
+			token:  "IncorrectSecret",
+			wantOk: false,
+		},
+		{
+			name:   "TestMatchSecretWithEmptyPreviousSecret",
+			fields: fields{secret: gitlabTestSecret, previousSecrets: []string{""}},
+			token:  " ",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GitlabProvider{
+				secret:          tt.fields.secret,
+				previousSecrets: tt.fields.previousSecrets,
+			}
+			hook := Hook{Headers: map[string]string{XGitlabToken: tt.token}}
+			gotIndex, gotOk := p.MatchSecret(hook)
+			if gotIndex != tt.wantIndex || gotOk != tt.wantOk {
+				t.Errorf("GitlabProvider.MatchSecret() = %v %v, want %v %v", gotIndex, gotOk, tt.wantIndex, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGitlabProvider_GetRepository(t *testing.T) {
+	type args struct {
+		hook Hook
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "TestGetRepositoryWithValidPayload",
+			args: args{
+				hook: Hook{
+					Payload: []byte(`{"project":{"path_with_namespace":"stakater/gitwebhookproxy"}}`),
+				},
+			},
+			want: "stakater/gitwebhookproxy",
+		},
+		{
+			name: "TestGetRepositoryWithInvalidPayload",
+			args: args{
+				hook: Hook{
+					Payload: []byte(`invalid`),
+				},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GitlabProvider{}
+			if got := p.GetRepository(tt.args.hook); got != tt.want {
+				t.Errorf("GitlabProvider.GetRepository() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitlabProvider_GetChangedFiles(t *testing.T) {
+	type args struct {
+		hook Hook
+	}
+	tests := []struct {
+		name   string
+		args   args
+		want   []string
+		wantOk bool
+	}{
+		{
+			name: "TestGetChangedFilesWithPushEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitlabEvent: string(GitlabPushEvent)},
+					Payload: []byte(`{"commits":[{"added":["services/billing/main.go"]},{"removed":["README.md"]}]}`),
+				},
+			},
+			want:   []string{"services/billing/main.go", "README.md"},
+			wantOk: true,
+		},
+		{
+			name: "TestGetChangedFilesWithPushEventWithoutCommits",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitlabEvent: string(GitlabPushEvent)},
+					Payload: []byte(`{"commits":[],"total_commits_count":0}`),
+				},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+		{
+			name: "TestGetChangedFilesWithTruncatedPushEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitlabEvent: string(GitlabPushEvent)},
+					Payload: []byte(`{"commits":[{"added":["services/billing/main.go"]}],"total_commits_count":21}`),
+				},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+		{
+			name: "TestGetChangedFilesWithTagPushEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitlabEvent: string(GitlabTagPushEvent)},
+					Payload: []byte(`{"commits":[]}`),
+				},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GitlabProvider{}
+			got, gotOk := p.GetChangedFiles(tt.args.hook)
+			if !reflect.DeepEqual(got, tt.want) || gotOk != tt.wantOk {
+				t.Errorf("GitlabProvider.GetChangedFiles() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGitlabProvider_GetAction(t *testing.T) {
+	type args struct {
+		hook Hook
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "TestGetActionWithMergeRequestEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitlabEvent: string(GitlabMergeRequestEvent)},
+					Payload: []byte(`{"object_attributes":{"action":"update"}}`),
+				},
+			},
+			want: "update",
+		},
+		{
+			name: "TestGetActionWithPushEvent",
+			args: args{
+				hook: Hook{
+					Headers: map[string]string{XGitlabEvent: string(GitlabPushEvent)},
+					Payload: []byte(`{"ref":"refs/heads/main"}`),
+				},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GitlabProvider{}
+			if got := p.GetAction(tt.args.hook); got != tt.want {
+				t.Errorf("GitlabProvider.GetAction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
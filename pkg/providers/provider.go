@@ -2,7 +2,9 @@ package providers
 
 import (
 	"errors"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -17,9 +19,51 @@ type Event string
 
 type Provider interface {
 	GetHeaderKeys() []string
+	GetOptionalHeaderKeys() []string
 	Validate(hook Hook) bool
+	MatchSecret(hook Hook) (int, bool)
+	Sign(hook *Hook)
 	GetCommitter(hook Hook) string
 	GetProviderName() string
+	GetEventType(hook Hook) Event
+	GetAction(hook Hook) string
+	GetDeliveryID(hook Hook) string
+	GetRepository(hook Hook) string
+	GetRef(hook Hook) string
+	IsPush(hook Hook) bool
+	GetHeadRef(hook Hook) string
+	GetChangedFiles(hook Hook) ([]string, bool)
+	GetHeadCommitMessage(hook Hook) string
+	IsDraft(hook Hook) bool
+	IsBot(hook Hook) bool
+	GetTimestamp(hook Hook) (time.Time, bool)
+}
+
+// SecretCurrent names the secret at index 0 returned by MatchSecret, while
+// previous secrets are named "previous-1", "previous-2" and so on
+const SecretCurrent = "current"
+
+// SecretName names the secret at index of MatchSecret for logs and metrics,
+// without revealing it
+func SecretName(index int) string {
+	if index == 0 {
+		return SecretCurrent
+	}
+	return "previous-" + strconv.Itoa(index)
+}
+
+// matchSecret returns the index of the first secret accepted by valid, the
+// current secret being 0. Empty previous secrets are skipped.
+func matchSecret(secret string, previousSecrets []string, valid func(secret string) bool) (int, bool) {
+	if valid(secret) {
+		return 0, true
+	}
+	for i, previous := range previousSecrets {
+		if len(strings.TrimSpace(previous)) > 0 && valid(previous) {
+			return i + 1, true
+		}
+	}
+	return 0, false
 }
 
 func assertProviderImplementations() {
@@ -27,21 +71,43 @@ func assertProviderImplementations() {
 	var _ Provider = (*GitlabProvider)(nil)
 }
 
-func NewProvider(provider string, secret string) (Provider, error) {
+// NewProvider creates the provider of the given kind. Hooks are signed with
+// secret, and validated with secret or any of previousSecrets, so that
+// secrets can be rotated without failing deliveries.
+func NewProvider(provider string, secret string, previousSecrets ...string) (Provider, error) {
 	if len(provider) == 0 {
 		return nil, errors.New("Empty provider string specified")
 	}
 
 	switch strings.ToLower(provider) {
 	case GithubProviderKind:
-		return NewGithubProvider(secret)
+		return NewGithubProvider(secret, previousSecrets...)
 	case GitlabProviderKind:
-		return NewGitlabProvider(secret)
+		return NewGitlabProvider(secret, previousSecrets...)
 	default:
 		return nil, errors.New("Unknown Git Provider '" + provider + "' specified")
 	}
 }
 
+// Prefixes of fully qualified git references
+const (
+	BranchRefPrefix = "refs/heads/"
+	TagRefPrefix    = "refs/tags/"
+)
+
+// appendChangedFiles adds the files that are not in files yet
+func appendChangedFiles(files []string, seen map[string]bool, changed ...[]string) []string {
+	for _, list := range changed {
+		for _, file := range list {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
 type Hook struct {
 	Payload       []byte
 	Headers       map[string]string
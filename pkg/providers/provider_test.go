@@ -84,3 +84,11 @@ func TestNewProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestSecretName(t *testing.T) {
+	for index, want := range []string{SecretCurrent, "previous-1", "previous-2"} {
+		if got := SecretName(index); got != want {
+			t.Errorf("SecretName(%v) = %v, want %v", index, got, want)
+		}
+	}
+}
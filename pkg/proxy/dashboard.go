@@ -0,0 +1,192 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+	"github.com/stakater/GitWebhookProxy/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//go:embed web/*.html
+var webFS embed.FS
+
+var dashboardTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"formatTime":  func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+	"prettyJSON":  prettyJSON,
+	"isSucceeded": func(decision string) bool { return decision == logging.DecisionForwarded },
+}).ParseFS(webFS, "web/*.html"))
+
+// dashboard lists the recent deliveries, accepting the same filters as /api/deliveries
+func (p *Proxy) dashboard(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	query, err := parseDeliveryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := p.history.List(query)
+	if err != nil {
+		http.Error(w, "Error listing deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, "deliveries.html", map[string]interface{}{
+		"Deliveries": deliveries,
+		"Query":      r.URL.Query(),
+	})
+}
+
+// dashboardDelivery shows the payload and upstream response of a single delivery
+func (p *Proxy) dashboardDelivery(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	record, ok := p.lookupDelivery(w, params)
+	if !ok {
+		return
+	}
+
+	renderTemplate(w, "delivery.html", map[string]interface{}{
+		"Delivery": record,
+	})
+}
+
+// dashboardRedeliver forwards a recorded delivery again and shows the new delivery
+func (p *Proxy) dashboardRedeliver(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !isSameOrigin(r) {
+		http.Error(w, "Cross-origin redelivery is not allowed", http.StatusForbidden)
+		return
+	}
+
+	record, ok := p.lookupDelivery(w, params)
+	if !ok {
+		return
+	}
+	// Unvalidated payloads may be forged, and would be signed with the secret
+	if !record.Validated {
+		http.Error(w, "Only validated deliveries can be redelivered", http.StatusForbidden)
+		return
+	}
+
+	d, err := p.redeliver(r.Context(), record)
+	if err != nil {
+		http.Error(w, "Error redelivering: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/ui/deliveries/"+strconv.FormatUint(d.record.ID, 10), http.StatusSeeOther)
+}
+
+// redeliver forwards a recorded delivery to the upstream again, bypassing the
+// filters. Secrets were redacted when the delivery was recorded, so the
+// payload is signed again with the configured secret.
+func (p *Proxy) redeliver(ctx context.Context, record *history.Delivery) (*delivery, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "redeliver",
+		trace.WithAttributes(tracing.ProviderKey.String(p.provider)))
+	defer span.End()
+
+	secret, _ := p.currentSecrets()
+	provider, err := providers.NewProvider(p.provider, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &providers.Hook{
+		Headers:       map[string]string{},
+		Payload:       []byte(record.Payload),
+		RequestMethod: http.MethodPost,
+	}
+	for key, value := range record.Headers {
+		if !logging.IsSensitiveKey(key) {
+			hook.Headers[key] = value
+		}
+	}
+	provider.Sign(hook)
+
+	d := newDelivery(span, p.provider, record.Path)
+	d.identify(provider, hook, record.Committer)
+	d.record.Validated = true
+	defer p.recordDelivery(d)
+
+	reason := "Redelivered delivery #" + strconv.FormatUint(record.ID, 10)
+	route, _ := p.isPathAllowed(record.Path)
+	upstreamHook, redirectURL, err := p.prepareUpstream(route, hook, p.upstreamURLFor(route, record.Path, ""))
+	if err != nil {
+		d.decide(slog.LevelError, logging.DecisionFailed, reason, err)
+		return d, nil
+	}
+	resp, _, err := p.forward(ctx, d, route, upstreamHook, redirectURL)
+	switch {
+	case resp == nil:
+		d.decide(slog.LevelError, logging.DecisionFailed, reason, err)
+	case resp.StatusCode >= 400:
+		d.decide(slog.LevelError, logging.DecisionFailed, reason+" but upstream rejected it", nil)
+	default:
+		d.decide(slog.LevelInfo, logging.DecisionForwarded, reason, nil)
+	}
+	return d, nil
+}
+
+func (p *Proxy) lookupDelivery(w http.ResponseWriter, params httprouter.Params) (*history.Delivery, bool) {
+	id, err := history.ParseID(params.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid delivery id '"+params.ByName("id")+"'", http.StatusBadRequest)
+		return nil, false
+	}
+
+	record, err := p.history.Get(id)
+	if err == history.ErrNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error reading delivery", http.StatusInternalServerError)
+		return nil, false
+	}
+	return record, true
+}
+
+// isSameOrigin rejects form posts sent by other sites on behalf of a user, and
+// posts without Origin nor Referer, which browsers always send with forms
+func isSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	if origin == "" {
+		return false
+	}
+
+	originURL, err := url.Parse(origin)
+	return err == nil && originURL.Host == r.Host
+}
+
+func renderTemplate(w http.ResponseWriter, name string, data interface{}) {
+	buf := &bytes.Buffer{}
+	if err := dashboardTemplates.ExecuteTemplate(buf, name, data); err != nil {
+		slog.Error("Error rendering dashboard", logging.ErrorKey, err)
+		http.Error(w, "Error rendering dashboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func prettyJSON(payload string) string {
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, []byte(payload), "", "  "); err != nil {
+		return payload
+	}
+	return buf.String()
+}
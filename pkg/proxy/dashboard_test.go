@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestProxy_dashboard(t *testing.T) {
+	receivedTokens := []string{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedTokens = append(receivedTokens, r.Header.Get(providers.XGitlabToken))
+		w.Write([]byte("build scheduled"))
+	}))
+	defer upstream.Close()
+
+	p := &Proxy{
+		provider:     providers.GitlabProviderKind,
+		upstreamURL:  upstream.URL,
+		allowedPaths: []string{},
+		secret:       proxyGitlabTestSecret,
+		history:      createTestHistory(t),
+	}
+	handler := p.adminHandler()
+
+	p.handler().ServeHTTP(httptest.NewRecorder(), createGitlabRequestWithPayload(http.MethodPost, "/project",
+		proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+	p.handler().ServeHTTP(httptest.NewRecorder(), createGitlabRequestWithPayload(http.MethodPost, "/project",
+		"ForgedSecret", string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		origin         string
+		wantStatusCode int
+		wantBody       string
+		wantLocation   string
+	}{
+		{
+			name:           "TestDashboardListsDeliveries",
+			method:         http.MethodGet,
+			path:           "/ui/deliveries",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "mike/diaspora",
+		},
+		{
+			name:           "TestDashboardShowsDelivery",
+			method:         http.MethodGet,
+			path:           "/ui/deliveries/1",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "build scheduled",
+		},
+		{
+			name:           "TestDashboardWithUnknownDelivery",
+			method:         http.MethodGet,
+			path:           "/ui/deliveries/42",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "TestDashboardRedeliverFromOtherOrigin",
+			method:         http.MethodPost,
+			path:           "/ui/deliveries/1/redeliver",
+			origin:         "https://attacker.example.com",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "TestDashboardRedeliverWithoutOrigin",
+			method:         http.MethodPost,
+			path:           "/ui/deliveries/1/redeliver",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "TestDashboardRedeliverUnvalidatedDelivery",
+			method:         http.MethodPost,
+			path:           "/ui/deliveries/2/redeliver",
+			origin:         "http://example.com",
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       "Only validated deliveries can be redelivered",
+		},
+		{
+			name:           "TestDashboardRedeliver",
+			method:         http.MethodPost,
+			path:           "/ui/deliveries/1/redeliver",
+			origin:         "http://example.com",
+			wantStatusCode: http.StatusSeeOther,
+			wantLocation:   "/ui/deliveries/3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatusCode)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("handler returned body without %q", tt.wantBody)
+			}
+			if location := rr.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("handler redirected to %q, want %q", location, tt.wantLocation)
+			}
+		})
+	}
+
+	if len(receivedTokens) != 2 || receivedTokens[1] != proxyGitlabTestSecret {
+		t.Errorf("upstream received tokens %v, want the redelivery signed with the secret", receivedTokens)
+	}
+}
+
+func TestProxy_handlerWithoutAdmin(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	p := &Proxy{
+		provider:     providers.GitlabProviderKind,
+		upstreamURL:  upstream.URL,
+		allowedPaths: []string{},
+		history:      createTestHistory(t),
+	}
+	handler := p.handler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/deliveries", nil))
+	if rr.Code == http.StatusOK {
+		t.Errorf("handler served the delivery API on the webhook listener")
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, createGitlabRequestWithPayload(http.MethodPost, "/ui/deliveries/1/redeliver",
+		proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+	if rr.Code != http.StatusOK || rr.Body.String() != "upstream says hi" {
+		t.Errorf("handler = %v %q, want the webhook proxied", rr.Code, rr.Body.String())
+	}
+}
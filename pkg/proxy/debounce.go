@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/metrics"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+// debounceKey returns the key under which hook is held, when it is a push to
+// a branch received on a route with a debounce window. Pushes are held per
+// path too, since each path may be forwarded to another upstream path.
+func debounceKey(route *config.Route, path string, provider providers.Provider, hook *providers.Hook) (string, bool) {
+	if route == nil || route.Debounce <= 0 || !provider.IsPush(*hook) {
+		return "", false
+	}
+
+	ref := provider.GetRef(*hook)
+	if !strings.HasPrefix(ref, providers.BranchRefPrefix) {
+		return "", false
+	}
+	return path + " " + provider.GetRepository(*hook) + " " + ref, true
+}
+
+// debounce holds the push of the delivery d for the debounce window of route.
+// When the window closes, the latest push held for key is forwarded, once the
+// rate limits of route allow it, and the pushes it replaced are coalesced.
+func (p *Proxy) debounce(ctx context.Context, d *delivery, route *config.Route, key string, rateLimitKeys []string,
+	provider providers.Provider, hook *providers.Hook, upstreamHook *providers.Hook, upstreamURL string) {
+	followUp := d.followUp(ctx, "Proxy.debounce")
+	p.deferred.hold(key, route.Debounce, &heldHook{
+		forward: func() {
+			ctx, later := followUp()
+			defer later.span.End()
+			defer p.recordDelivery(later)
+
+			delay, limit, ok := p.reserveRateLimits(route, rateLimitKeys, time.Now())
+			if !ok {
+				p.forgetDelivery(later, provider, hook)
+				metrics.RateLimitedDeliveries.WithLabelValues(limit.Key, config.RateLimitReject).Inc()
+				later.decide(slog.LevelWarn, logging.DecisionRejected, "Rate limit by "+limit.Key+" exceeded", nil)
+				return
+			}
+			if delay > 0 {
+				p.forwardLater(ctx, later, route, provider, hook, upstreamHook, upstreamURL, delay)
+				metrics.RateLimitedDeliveries.WithLabelValues(limit.Key, config.RateLimitDelay).Inc()
+				later.decide(slog.LevelInfo, logging.DecisionDeferred, "Rate limit by "+limit.Key+" exceeded, deferring by "+delay.String(), nil)
+				return
+			}
+			p.forwardDetached(ctx, later, route, provider, hook, upstreamHook, upstreamURL, "debounced")
+		},
+		coalesce: func() {
+			_, later := followUp()
+			defer later.span.End()
+			defer p.recordDelivery(later)
+
+			later.decide(slog.LevelInfo, logging.DecisionCoalesced, "Superseded by a later push", nil)
+		},
+	})
+}
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func createTestDebounceProxy(t *testing.T, upstreamURL string, options ...Option) (*Proxy, *httprouter.Router) {
+	cfg, err := config.Parse([]byte("routes:\n  - path: /gitlab\n    debounce: 50ms\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProxy(upstreamURL, []string{}, providers.GitlabProviderKind, "", []string{},
+		append(options, WithRoutes(cfg.Routes))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+	return p, router
+}
+
+func createGitlabPushRequest(path string, event providers.Event, ref string, deliveryID string) *http.Request {
+	payload := bytes.Replace(proxyGitlabTestPayload, []byte("refs/heads/master"), []byte(ref), 1)
+	req := createGitlabRequestWithPayload(http.MethodPost, path, proxyGitlabTestSecret, string(event), payload)
+	req.Header.Set(providers.XGitlabEventUUID, deliveryID)
+	return req
+}
+
+func TestProxy_proxyRequestWithDebounce(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		event           providers.Event
+		refs            []string
+		wantStatusCodes []int
+		wantForwarded   []string
+	}{
+		{
+			name:            "TestDebounceCoalescesPushes",
+			path:            "/gitlab",
+			event:           providers.GitlabPushEvent,
+			refs:            []string{"refs/heads/master", "refs/heads/master", "refs/heads/master"},
+			wantStatusCodes: []int{http.StatusAccepted, http.StatusAccepted, http.StatusAccepted},
+			wantForwarded:   []string{"push-3"},
+		},
+		{
+			name:            "TestDebouncePerBranch",
+			path:            "/gitlab",
+			event:           providers.GitlabPushEvent,
+			refs:            []string{"refs/heads/master", "refs/heads/develop", "refs/heads/master"},
+			wantStatusCodes: []int{http.StatusAccepted, http.StatusAccepted, http.StatusAccepted},
+			wantForwarded:   []string{"push-2", "push-3"},
+		},
+		{
+			name:            "TestDebounceSkipsTags",
+			path:            "/gitlab",
+			event:           providers.GitlabTagPushEvent,
+			refs:            []string{"refs/tags/v1.0", "refs/tags/v1.0"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusOK},
+			wantForwarded:   []string{"push-1", "push-2"},
+		},
+		{
+			name:            "TestDebounceSkipsPathsWithoutRoute",
+			path:            "/post",
+			event:           providers.GitlabPushEvent,
+			refs:            []string{"refs/heads/master", "refs/heads/master"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusOK},
+			wantForwarded:   []string{"push-1", "push-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			forwarded := []string{}
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				forwarded = append(forwarded, r.Header.Get(providers.XGitlabEventUUID))
+			}))
+			defer upstream.Close()
+			p, router := createTestDebounceProxy(t, upstream.URL)
+
+			for i, ref := range tt.refs {
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, createGitlabPushRequest(tt.path, tt.event, ref, "push-"+strconv.Itoa(i+1)))
+				if rr.Code != tt.wantStatusCodes[i] {
+					t.Errorf("Proxy.proxyRequest() #%v = %v %q, want %v", i, rr.Code, rr.Body.String(), tt.wantStatusCodes[i])
+				}
+			}
+
+			p.deferred.wait()
+			sort.Strings(forwarded)
+			if !reflect.DeepEqual(forwarded, tt.wantForwarded) {
+				t.Errorf("Proxy.proxyRequest() forwarded %v, want %v", forwarded, tt.wantForwarded)
+			}
+		})
+	}
+}
+
+func TestProxy_proxyRequestRecordsCoalescedDeliveries(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	store := createTestHistory(t)
+	p, router := createTestDebounceProxy(t, upstream.URL, WithHistory(store))
+
+	for i := 1; i <= 2; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), createGitlabPushRequest("/gitlab",
+			providers.GitlabPushEvent, "refs/heads/master", "push-"+strconv.Itoa(i)))
+	}
+
+	p.deferred.wait()
+
+	deliveries, err := store.List(history.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Deliveries are listed from the most recent
+	want := []string{
+		"push-2 " + logging.DecisionForwarded,
+		"push-2 " + logging.DecisionDeferred,
+		"push-1 " + logging.DecisionCoalesced,
+		"push-1 " + logging.DecisionDeferred,
+	}
+	got := []string{}
+	for _, d := range deliveries {
+		got = append(got, d.DeliveryID+" "+d.Decision)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+)
+
+// listDeliveries returns recorded deliveries, newest first. Supported query
+// parameters are repo, event, decision, status (upstream status code),
+// since and until (RFC 3339) and limit.
+func (p *Proxy) listDeliveries(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	query, err := parseDeliveryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := p.history.List(query)
+	if err != nil {
+		http.Error(w, "Error listing deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// getDelivery returns a single recorded delivery
+func (p *Proxy) getDelivery(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	id, err := history.ParseID(params.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid delivery id '"+params.ByName("id")+"'", http.StatusBadRequest)
+		return
+	}
+
+	d, err := p.history.Get(id)
+	if err == history.ErrNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error reading delivery", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, d)
+}
+
+func parseDeliveryQuery(values url.Values) (history.Query, error) {
+	query := history.Query{
+		Repository: values.Get("repo"),
+		Event:      values.Get("event"),
+		Decision:   values.Get("decision"),
+	}
+
+	var err error
+	if status := values.Get("status"); status != "" {
+		if query.UpstreamStatus, err = strconv.Atoi(status); err != nil {
+			return query, invalidQueryParameter("status", status)
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return query, invalidQueryParameter("limit", limit)
+		}
+	}
+	if since := values.Get("since"); since != "" {
+		if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return query, invalidQueryParameter("since", since)
+		}
+	}
+	if until := values.Get("until"); until != "" {
+		if query.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return query, invalidQueryParameter("until", until)
+		}
+	}
+	return query, nil
+}
+
+func invalidQueryParameter(parameter string, value string) error {
+	return errors.New("Invalid value '" + value + "' for query parameter '" + parameter + "'")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func createTestUpstream(t *testing.T, status int) *httptest.Server {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte("upstream says hi"))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func createTestHistory(t *testing.T) *history.Store {
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestProxy_listDeliveries(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	p := &Proxy{
+		provider:     providers.GitlabProviderKind,
+		upstreamURL:  upstream.URL,
+		allowedPaths: []string{"/post"},
+		secret:       proxyGitlabTestSecret,
+		history:      createTestHistory(t),
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+	router.GET("/api/deliveries", p.listDeliveries)
+	router.GET("/api/deliveries/:id", p.getDelivery)
+
+	requests := []*http.Request{
+		createGitlabRequestWithPayload(http.MethodPost, "/post",
+			proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload),
+		createGitlabRequestWithPayload(http.MethodPost, "/post",
+			"InvalidSecret", string(providers.GitlabPushEvent), proxyGitlabTestPayload),
+		createGitlabRequestWithPayload(http.MethodPost, "/notallowed",
+			proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload),
+	}
+	for _, req := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		wantStatusCode int
+		wantDecisions  []string
+	}{
+		{
+			name:           "TestListDeliveriesWithoutFilters",
+			query:          "",
+			wantStatusCode: http.StatusOK,
+			wantDecisions:  []string{logging.DecisionRejected, logging.DecisionRejected, logging.DecisionForwarded},
+		},
+		{
+			name:           "TestListDeliveriesByRepository",
+			query:          "?repo=mike/diaspora",
+			wantStatusCode: http.StatusOK,
+			wantDecisions:  []string{logging.DecisionRejected, logging.DecisionForwarded},
+		},
+		{
+			name:           "TestListDeliveriesByUpstreamStatus",
+			query:          "?status=200",
+			wantStatusCode: http.StatusOK,
+			wantDecisions:  []string{logging.DecisionForwarded},
+		},
+		{
+			name:           "TestListDeliveriesWithInvalidTimeRange",
+			query:          "?since=yesterday",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/deliveries"+tt.query, nil))
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatusCode)
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+
+			deliveries := []*history.Delivery{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &deliveries); err != nil {
+				t.Fatal(err)
+			}
+			if len(deliveries) != len(tt.wantDecisions) {
+				t.Fatalf("listDeliveries() returned %v deliveries, want %v", len(deliveries), len(tt.wantDecisions))
+			}
+			for i, d := range deliveries {
+				if d.Decision != tt.wantDecisions[i] {
+					t.Errorf("listDeliveries()[%v].Decision = %v, want %v", i, d.Decision, tt.wantDecisions[i])
+				}
+				if d.Headers[providers.XGitlabToken] == proxyGitlabTestSecret {
+					t.Errorf("listDeliveries()[%v] exposed the secret token", i)
+				}
+			}
+		})
+	}
+}
+
+func TestProxy_getDelivery(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusInternalServerError)
+	p := &Proxy{
+		provider:     providers.GitlabProviderKind,
+		upstreamURL:  upstream.URL,
+		allowedPaths: []string{},
+		history:      createTestHistory(t),
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+	router.GET("/api/deliveries/:id", p.getDelivery)
+
+	router.ServeHTTP(httptest.NewRecorder(), createGitlabRequestWithPayload(http.MethodPost, "/post",
+		proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+
+	tests := []struct {
+		name           string
+		id             string
+		wantStatusCode int
+	}{
+		{
+			name:           "TestGetDeliveryWithValidID",
+			id:             "1",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "TestGetDeliveryWithUnknownID",
+			id:             "42",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "TestGetDeliveryWithInvalidID",
+			id:             "first",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/deliveries/"+tt.id, nil))
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatusCode)
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+
+			d := &history.Delivery{}
+			if err := json.Unmarshal(rr.Body.Bytes(), d); err != nil {
+				t.Fatal(err)
+			}
+			if d.UpstreamStatus != http.StatusInternalServerError || d.ResponseSnippet != "upstream says hi" {
+				t.Errorf("getDelivery() = %v %q, want upstream status and response recorded",
+					d.UpstreamStatus, d.ResponseSnippet)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/metrics"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+	"github.com/stakater/GitWebhookProxy/pkg/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// delivery follows a single webhook through the proxy, so that its outcome
+// ends up consistently in the logs, the request span and the delivery history
+type delivery struct {
+	logger *slog.Logger
+	span   trace.Span
+	record *history.Delivery
+}
+
+func newDelivery(span trace.Span, provider string, path string) *delivery {
+	return &delivery{
+		// Query strings may carry upstream tokens, so only the path is logged
+		logger: slog.With(logging.ProviderKey, provider, logging.PathKey, path),
+		span:   span,
+		record: &history.Delivery{
+			ReceivedAt: time.Now(),
+			Provider:   provider,
+			Path:       path,
+		},
+	}
+}
+
+// identify attaches the parsed hook and the fields derived from it
+func (d *delivery) identify(provider providers.Provider, hook *providers.Hook, committer string) {
+	deliveryID := provider.GetDeliveryID(*hook)
+	if deliveryID == "" {
+		deliveryID = newDeliveryID()
+	}
+	eventType := string(provider.GetEventType(*hook))
+	repository := provider.GetRepository(*hook)
+
+	d.logger = d.logger.With(
+		logging.DeliveryIDKey, deliveryID,
+		logging.EventKey, eventType,
+		logging.RepositoryKey, repository,
+		logging.CommitterKey, committer,
+	)
+	d.span.SetAttributes(
+		tracing.DeliveryIDKey.String(deliveryID),
+		tracing.EventKey.String(eventType),
+		tracing.RepositoryKey.String(repository),
+	)
+
+	d.record.DeliveryID = deliveryID
+	d.record.Event = eventType
+	d.record.Repository = repository
+	d.record.Committer = committer
+	d.record.Headers = hook.Headers
+	d.record.Payload = string(hook.Payload)
+}
+
+// validated records the name of the secret that validated the hook
+func (d *delivery) validated(secretName string) {
+	d.logger = d.logger.With(logging.ValidatedWithKey, secretName)
+	d.record.Validated = true
+	metrics.ValidatedDeliveries.WithLabelValues(d.record.Provider, secretName).Inc()
+}
+
+// upstreamResponded records the upstream answer to the forwarded hook
+func (d *delivery) upstreamResponded(status int, latency time.Duration, body []byte) {
+	d.logger = d.logger.With(logging.UpstreamStatusKey, status)
+	d.span.SetAttributes(tracing.UpstreamStatusKey.Int(status))
+
+	d.record.UpstreamStatus = status
+	d.record.LatencyMillis = latency.Milliseconds()
+	d.record.ResponseSnippet = string(body)
+}
+
+// decide logs the outcome of the delivery and records it on the span and in the history
+func (d *delivery) decide(level slog.Level, decision string, reason string, err error) {
+	args := []any{logging.DecisionKey, decision}
+	if err != nil {
+		args = append(args, logging.ErrorKey, err)
+		reason += ": " + err.Error()
+	}
+	d.logger.Log(context.Background(), level, reason, args...)
+
+	d.span.SetAttributes(tracing.DecisionKey.String(decision))
+	if decision == logging.DecisionFailed {
+		d.span.SetStatus(codes.Error, reason)
+	}
+
+	d.record.Decision = decision
+	d.record.Reason = reason
+
+	metrics.Deliveries.WithLabelValues(d.record.Provider, decision).Inc()
+}
+
+// followUp returns a function continuing d as a delivery of its own, for
+// hooks forwarded after their request was answered. The delivery is copied as
+// it is now, and continued in a span linked to the span of ctx.
+func (d *delivery) followUp(ctx context.Context, spanName string) func() (context.Context, *delivery) {
+	link := trace.LinkFromContext(ctx)
+	logger, record := d.logger, *d.record
+	return func() (context.Context, *delivery) {
+		ctx, span := tracing.Tracer().Start(context.Background(), spanName, trace.WithLinks(link),
+			trace.WithAttributes(
+				tracing.ProviderKey.String(record.Provider),
+				tracing.DeliveryIDKey.String(record.DeliveryID),
+			))
+		record := record
+		return ctx, &delivery{logger: logger, span: span, record: &record}
+	}
+}
+
+// newDeliveryID generates a correlation id for providers that do not send one
+func newDeliveryID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+
+	configComponent   = "config"
+	upstreamComponent = "upstream"
+	queueComponent    = "queue"
+
+	upstreamProbeTimeout = 5 * time.Second
+)
+
+// componentStatus reports the state of one part of the proxy
+type componentStatus struct {
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+type healthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentStatus `json:"components"`
+}
+
+// healthCheck reports the state of a component registered by the feature owning it
+type healthCheck func(ctx context.Context) componentStatus
+
+// configLoad is the result of the latest load of one configuration source
+type configLoad struct {
+	LoadedAt time.Time `json:"loadedAt"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// configState tracks the load results of every configuration source
+type configState struct {
+	mutex sync.Mutex
+	loads map[string]configLoad
+}
+
+func (c *configState) report(source string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.loads == nil {
+		c.loads = map[string]configLoad{}
+	}
+	load := configLoad{LoadedAt: time.Now()}
+	if err != nil {
+		load.Error = err.Error()
+	}
+	c.loads[source] = load
+}
+
+func (c *configState) check(ctx context.Context) componentStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	status := componentStatus{Status: StatusOK, Details: map[string]interface{}{}}
+	for source, load := range c.loads {
+		status.Details[source] = load
+		if load.Error != "" {
+			status.Status = StatusUnavailable
+			status.Error = "Error loading " + source + ": " + load.Error
+		}
+	}
+	return status
+}
+
+// upstreamProbe checks that the upstream answers on a path with an expected
+// status. Results are cached for interval to avoid flooding the upstream.
+type upstreamProbe struct {
+	path           string
+	expectedStatus int
+	interval       time.Duration
+
+	mutex     sync.Mutex
+	checkedAt time.Time
+	last      componentStatus
+}
+
+func (u *upstreamProbe) check(ctx context.Context, upstreamURL string) componentStatus {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if !u.checkedAt.IsZero() && time.Since(u.checkedAt) < u.interval {
+		return u.last
+	}
+
+	u.last = u.probe(ctx, upstreamURL+u.path)
+	u.checkedAt = time.Now()
+	return u.last
+}
+
+func (u *upstreamProbe) probe(ctx context.Context, probeURL string) componentStatus {
+	ctx, cancel := context.WithTimeout(ctx, upstreamProbeTimeout)
+	defer cancel()
+
+	status := componentStatus{
+		Status: StatusUnavailable,
+		Details: map[string]interface{}{
+			"path":      u.path,
+			"checkedAt": time.Now(),
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	status.Details["statusCode"] = resp.StatusCode
+	status.Details["latencyMs"] = time.Since(start).Milliseconds()
+
+	// Without an expected status any answer short of a server error will do
+	if (u.expectedStatus == 0 && resp.StatusCode < 500) || resp.StatusCode == u.expectedStatus {
+		status.Status = StatusOK
+		return status
+	}
+	status.Error = "Upstream answered probe with status " + strconv.Itoa(resp.StatusCode)
+	return status
+}
+
+// WithUpstreamProbe makes readiness depend on the upstream answering GET
+// requests on path with expectedStatus, or any non 5xx status if it is 0
+func WithUpstreamProbe(path string, expectedStatus int, interval time.Duration) Option {
+	return func(p *Proxy) {
+		p.upstreamProbe = &upstreamProbe{
+			path:           path,
+			expectedStatus: expectedStatus,
+			interval:       interval,
+		}
+	}
+}
+
+// registerHealthCheck adds a component to the readiness report
+func (p *Proxy) registerHealthCheck(component string, check healthCheck) {
+	if p.healthChecks == nil {
+		p.healthChecks = map[string]healthCheck{}
+	}
+	p.healthChecks[component] = check
+}
+
+func (p *Proxy) healthReport(ctx context.Context) healthReport {
+	report := healthReport{
+		Status: StatusOK,
+		Components: map[string]componentStatus{
+			configComponent: p.configState.check(ctx),
+		},
+	}
+	if p.upstreamProbe != nil {
+		report.Components[upstreamComponent] = p.upstreamProbe.check(ctx, p.upstreamURL)
+	}
+	for component, check := range p.healthChecks {
+		report.Components[component] = check(ctx)
+	}
+
+	for _, status := range report.Components {
+		if status.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// Readiness Check Endpoint, failing while any component is unavailable
+func (p *Proxy) ready(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	report := p.healthReport(r.Context())
+	if report.Status != StatusOK {
+		writeJSON(w, http.StatusServiceUnavailable, report)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestProxy_ready(t *testing.T) {
+	type fields struct {
+		upstreamStatus int
+		expectedStatus int
+		configError    error
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		wantStatusCode int
+		wantUnhealthy  string
+	}{
+		{
+			name: "TestReadyWithReachableUpstream",
+			fields: fields{
+				upstreamStatus: http.StatusForbidden,
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "TestReadyWithFailingUpstream",
+			fields: fields{
+				upstreamStatus: http.StatusBadGateway,
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantUnhealthy:  upstreamComponent,
+		},
+		{
+			name: "TestReadyWithUnexpectedUpstreamStatus",
+			fields: fields{
+				upstreamStatus: http.StatusNotFound,
+				expectedStatus: http.StatusOK,
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantUnhealthy:  upstreamComponent,
+		},
+		{
+			name: "TestReadyWithBrokenConfig",
+			fields: fields{
+				upstreamStatus: http.StatusOK,
+				configError:    errors.New("invalid"),
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantUnhealthy:  configComponent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := createTestUpstream(t, tt.fields.upstreamStatus)
+			p, err := NewProxy(upstream.URL, []string{}, providers.GithubProviderKind, "", []string{},
+				WithUpstreamProbe("/login", tt.fields.expectedStatus, time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.fields.configError != nil {
+				p.configState.report("config.yaml", tt.fields.configError)
+			}
+
+			router := httprouter.New()
+			router.GET("/ready", p.ready)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			if rr.Code != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatusCode)
+			}
+
+			report := healthReport{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			for component, status := range report.Components {
+				if (status.Status != StatusOK) != (component == tt.wantUnhealthy) {
+					t.Errorf("ready() component %v status = %v", component, status.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestProxy_healthVerbose(t *testing.T) {
+	p := &Proxy{}
+	p.registerHealthCheck("queue", func(ctx context.Context) componentStatus {
+		return componentStatus{Status: StatusUnavailable}
+	})
+
+	router := httprouter.New()
+	router.GET("/health", p.health)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health?verbose=1", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	report := healthReport{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != StatusUnavailable || report.Components["queue"].Status != StatusUnavailable {
+		t.Errorf("health() report = %+v, want unavailable queue", report)
+	}
+}
+
+func TestProxy_readyWithQueue(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	p, router := createTestDebounceProxy(t, upstream.URL)
+	router.GET("/ready", p.ready)
+
+	queue := func() map[string]interface{} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		report := healthReport{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		return report.Components[queueComponent].Details
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), createGitlabPushRequest("/gitlab",
+		providers.GitlabPushEvent, "refs/heads/master", "push-1"))
+	p.deferred.after(50*time.Millisecond, func() {})
+	if got := queue(); got["held"] != 1.0 || got["delayed"] != 1.0 {
+		t.Errorf("ready() queue = %v, want 1 held and 1 delayed hook", got)
+	}
+
+	p.deferred.wait()
+	if got := queue(); got["held"] != 0.0 || got["delayed"] != 0.0 {
+		t.Errorf("ready() queue = %v, want no hook", got)
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+)
+
+// Limits protects the listener from oversized, slow or too many requests.
+// Zero values disable the corresponding limit.
+type Limits struct {
+	// MaxBodyBytes is the size above which webhooks are rejected with 413
+	MaxBodyBytes int64
+	// Timeouts of the server, see http.Server
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// MaxConcurrentRequests is the number of webhooks handled at once, above
+	// which webhooks are rejected with 503
+	MaxConcurrentRequests int
+}
+
+// WithLimits applies limits to the listener and to the webhooks it receives
+func WithLimits(limits Limits) Option {
+	return func(p *Proxy) {
+		p.limits = limits
+		if limits.MaxConcurrentRequests > 0 {
+			p.inFlight = make(chan struct{}, limits.MaxConcurrentRequests)
+		}
+	}
+}
+
+// server returns the server of handler listening on listenAddress with the
+// timeouts of the limits
+func (p *Proxy) server(listenAddress string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              listenAddress,
+		Handler:           handler,
+		ReadHeaderTimeout: p.limits.ReadHeaderTimeout,
+		ReadTimeout:       p.limits.ReadTimeout,
+		WriteTimeout:      p.limits.WriteTimeout,
+		IdleTimeout:       p.limits.IdleTimeout,
+	}
+}
+
+// limitConcurrency rejects the requests received while MaxConcurrentRequests
+// are being handled, rather than queueing them
+func (p *Proxy) limitConcurrency(handle httprouter.Handle) httprouter.Handle {
+	if p.inFlight == nil {
+		return handle
+	}
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		select {
+		case p.inFlight <- struct{}{}:
+			defer func() { <-p.inFlight }()
+			handle(w, r, params)
+		default:
+			slog.Warn("Rejecting request, too many concurrent requests", logging.PathKey, r.URL.Path)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Too many concurrent requests", http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// limitBody makes reading the body of r fail once MaxBodyBytes are read
+func (p *Proxy) limitBody(w http.ResponseWriter, r *http.Request) {
+	if p.limits.MaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, p.limits.MaxBodyBytes)
+	}
+}
+
+// isBodyTooLarge reports whether err was caused by a body above MaxBodyBytes
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestProxy_proxyRequestWithMaxBodyBytes(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	tests := []struct {
+		name           string
+		maxBodyBytes   int64
+		wantStatusCode int
+	}{
+		{name: "TestProxyRequestWithoutLimit", maxBodyBytes: 0, wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithPayloadBelowLimit", maxBodyBytes: int64(len(proxyGitlabTestPayload)), wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithPayloadAboveLimit", maxBodyBytes: 64, wantStatusCode: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, proxyGitlabTestSecret, []string{},
+				WithLimits(Limits{MaxBodyBytes: tt.maxBodyBytes}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			p.handler().ServeHTTP(rr, createGitlabRequestWithPayload(http.MethodPost, "/post",
+				proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+
+			if rr.Code != tt.wantStatusCode {
+				t.Errorf("Proxy.proxyRequest() = %v %q, want %v", rr.Code, rr.Body.String(), tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestProxy_limitConcurrency(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(upstream.Close)
+
+	p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, proxyGitlabTestSecret, []string{},
+		WithLimits(Limits{MaxConcurrentRequests: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := p.handler()
+	send := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, createGitlabRequestWithPayload(http.MethodPost, "/post",
+			proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+		return rr
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- send() }()
+	<-entered
+
+	if rr := send(); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Proxy.limitConcurrency() with saturated proxy = %v, want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rr.Code == http.StatusServiceUnavailable {
+		t.Errorf("Proxy.limitConcurrency() limited the health check")
+	}
+
+	close(release)
+	if rr := <-first; rr.Code != http.StatusOK {
+		t.Errorf("Proxy.limitConcurrency() first request = %v, want %v", rr.Code, http.StatusOK)
+	}
+	go func() { <-entered }()
+	if rr := send(); rr.Code != http.StatusOK {
+		t.Errorf("Proxy.limitConcurrency() after release = %v, want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestProxy_server(t *testing.T) {
+	p := &Proxy{limits: Limits{
+		ReadHeaderTimeout: time.Second,
+		ReadTimeout:       2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+	}}
+	server := p.server(":8080", p.handler())
+	if server.ReadHeaderTimeout != time.Second || server.ReadTimeout != 2*time.Second ||
+		server.WriteTimeout != 3*time.Second || server.IdleTimeout != 4*time.Second {
+		t.Errorf("Proxy.server() timeouts = %v %v %v %v, want the limits", server.ReadHeaderTimeout,
+			server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+	}
+}
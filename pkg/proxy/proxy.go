@@ -2,20 +2,34 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/auth"
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/filters"
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/metrics"
+	"github.com/stakater/GitWebhookProxy/pkg/network"
 	"github.com/stakater/GitWebhookProxy/pkg/parser"
 	"github.com/stakater/GitWebhookProxy/pkg/providers"
-	"github.com/stakater/GitWebhookProxy/pkg/utils"
+	"github.com/stakater/GitWebhookProxy/pkg/replay"
+	"github.com/stakater/GitWebhookProxy/pkg/secrets"
+	"github.com/stakater/GitWebhookProxy/pkg/tracing"
+	"github.com/stakater/GitWebhookProxy/pkg/users"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -35,31 +49,173 @@ type Proxy struct {
 	secret       string
 	ignoredUsers []string
 	allowedUsers []string
+	// ignoredUserList and allowedUserList are compiled from ignoredUsers and
+	// allowedUsers when the proxy is created
+	ignoredUserList *users.List
+	allowedUserList *users.List
+	history         *history.Store
+	adminAddress    string
+	routes          []*config.Route
+	userGroups      *users.Groups
+
+	forwardAllHeaders  bool
+	upstreamAuth       auth.Authenticator
+	upstreamSecret     auth.Secret
+	previousSecrets    []string
+	secretFile         *secrets.File
+	sourceRanges       *network.Allowlist
+	trustedProxies     *network.Allowlist
+	seenDeliveries     *replay.Store
+	timestampTolerance time.Duration
+	limits             Limits
+	inFlight           chan struct{}
+	deferred           scheduler
+	redaction          *config.Redaction
+
+	configState   configState
+	upstreamProbe *upstreamProbe
+	healthChecks  map[string]healthCheck
 }
 
-func (p *Proxy) isPathAllowed(path string) bool {
+// Option configures optional features of a Proxy
+type Option func(*Proxy)
+
+// WithHistory records every delivery in store and exposes it through /api/deliveries
+func WithHistory(store *history.Store) Option {
+	return func(p *Proxy) {
+		p.history = store
+	}
+}
+
+// WithAdminListener serves the delivery API and dashboard of the history on
+// listenAddress, separately from the webhooks
+func WithAdminListener(listenAddress string) Option {
+	return func(p *Proxy) {
+		p.adminAddress = listenAddress
+	}
+}
+
+// WithRoutes applies the user policy and filters of each route to the webhooks
+// received on its path. Route paths are always allowed.
+func WithRoutes(routes []*config.Route) Option {
+	return func(p *Proxy) {
+		p.routes = routes
+	}
+}
+
+// WithAllowedUsers only forwards the webhooks of allowedUsers on paths
+// without a route user policy
+func WithAllowedUsers(allowedUsers []string) Option {
+	return func(p *Proxy) {
+		p.allowedUsers = allowedUsers
+	}
+}
+
+// WithPreviousSecrets also accepts the webhooks validated with one of
+// previousSecrets, while secrets are being rotated
+func WithPreviousSecrets(previousSecrets []string) Option {
+	return func(p *Proxy) {
+		p.previousSecrets = previousSecrets
+	}
+}
+
+// WithSecretFile reads the secret and the previous secrets from file instead,
+// so that they are picked up when the file is reloaded
+func WithSecretFile(file *secrets.File) Option {
+	return func(p *Proxy) {
+		p.secretFile = file
+	}
+}
+
+// currentSecrets returns the secret and the previous secrets with which hooks
+// are validated
+func (p *Proxy) currentSecrets() (string, []string) {
+	if p.secretFile != nil {
+		return p.secretFile.Secrets()
+	}
+	return p.secret, p.previousSecrets
+}
+
+// routeFor returns the route matching path most specifically, if any. Among
+// regex routes, the first one matching wins.
+func (p *Proxy) routeFor(path string) *config.Route {
+	var match *config.Route
+	matchScore := -1
+	for _, route := range p.routes {
+		if score, ok := route.MatchPath(path); ok && score > matchScore {
+			match, matchScore = route, score
+		}
+	}
+	return match
+}
+
+// upstreamURLFor returns the upstream URL of a hook received on path with
+// rawQuery, rewriting path with the route, if any. The queries of the rewritten
+// path and of the incoming request are both kept.
+func (p *Proxy) upstreamURLFor(route *config.Route, path string, rawQuery string) string {
+	upstreamPath := path
+	if route != nil {
+		upstreamPath = route.RewritePath(path)
+	}
+
+	upstreamPath, upstreamQuery, _ := strings.Cut(upstreamPath, "?")
+	if upstreamQuery != "" && rawQuery != "" {
+		upstreamQuery += "&"
+	}
+	upstreamQuery += rawQuery
+
+	redirectURL := p.upstreamURL + upstreamPath
+	if upstreamQuery != "" {
+		redirectURL += "?" + upstreamQuery
+	}
+	return redirectURL
+}
+
+// isPathAllowed tells whether path may be proxied, and returns the route
+// matching it, if any
+func (p *Proxy) isPathAllowed(path string) (*config.Route, bool) {
+	if route := p.routeFor(path); route != nil {
+		return route, true
+	}
+
 	// All paths allowed
 	if len(p.allowedPaths) == 0 {
-		return true
+		return nil, true
 	}
 
-	// Check if given passed exists in allowedPaths
+	// Check if given passed exists in allowedPaths. Like route prefixes, allowed
+	// paths end at a segment boundary, so /project does not allow /projectX.
+	incomingPath := strings.TrimSuffix(strings.TrimSpace(path), "/")
 	for _, p := range p.allowedPaths {
-		allowedPath := strings.TrimSpace(p)
-		incomingPath := strings.TrimSpace(path)
-		if strings.TrimSuffix(allowedPath, "/") ==
-			strings.TrimSuffix(incomingPath, "/") || strings.HasPrefix(incomingPath, allowedPath) {
-			return true
+		allowedPath := strings.TrimSuffix(strings.TrimSpace(p), "/")
+		if incomingPath == allowedPath || strings.HasPrefix(incomingPath, allowedPath+"/") {
+			return nil, true
 		}
 	}
-	return false
+	return nil, false
+}
+
+// WithUserGroups resolves the "group:" entries of user lists in groups
+func WithUserGroups(groups *users.Groups) Option {
+	return func(p *Proxy) {
+		p.userGroups = groups
+	}
+}
+
+// ReportConfigLoad records the result of loading a configuration source, such
+// as a reloaded file, for the readiness check
+func (p *Proxy) ReportConfigLoad(source string, err error) {
+	if err != nil {
+		slog.Error("Error loading configuration", "source", source, logging.ErrorKey, err)
+	} else {
+		slog.Info("Loaded configuration", "source", source)
+	}
+	p.configState.report(source, err)
 }
 
 func (p *Proxy) isIgnoredUser(committer string) bool {
-	if len(p.ignoredUsers) > 0 {
-		if exists, _ := utils.InArray(p.ignoredUsers, committer); exists {
-			return true
-		}
+	if p.ignoredUserList.Match(committer, p.userGroups) {
+		return true
 	}
 
 	if committer == "" && p.provider == providers.GithubName {
@@ -70,18 +226,36 @@ func (p *Proxy) isIgnoredUser(committer string) bool {
 }
 
 func (p *Proxy) isAllowedUser(committer string) bool {
-	if len(p.allowedUsers) > 0 {
-		if exists, _ := utils.InArray(p.allowedUsers, committer); exists {
-			return true
-		}
+	return p.allowedUserList.Empty() || p.allowedUserList.Match(committer, p.userGroups)
+}
 
-		return false
+// isUserAllowed applies the user policy of route, or the ignoredUsers and
+// allowedUsers of the proxy when the path has no route user policy
+func (p *Proxy) isUserAllowed(route *config.Route, committer string) bool {
+	if route == nil || route.Users == nil {
+		return !p.isIgnoredUser(committer) && p.isAllowedUser(committer)
 	}
 
-	return true
+	if committer == "" && p.provider == providers.GithubName {
+		return false
+	}
+	if route.IgnoredUsers.Match(committer, p.userGroups) {
+		return false
+	}
+	return route.AllowedUsers.Empty() || route.AllowedUsers.Match(committer, p.userGroups)
 }
 
-func (p *Proxy) redirect(hook *providers.Hook, redirectURL string) (*http.Response, error) {
+func (p *Proxy) redirect(ctx context.Context, hook *providers.Hook, redirectURL string,
+	authenticator auth.Authenticator) (resp *http.Response, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Proxy.redirect", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(tracing.UpstreamStatusKey.Int(resp.StatusCode))
+		}
+		err = withoutURLQuery(err)
+		tracing.EndSpan(span, err)
+	}()
+
 	if hook == nil {
 		return nil, errors.New("Cannot redirect with nil Hook")
 	}
@@ -98,7 +272,7 @@ func (p *Proxy) redirect(hook *providers.Hook, redirectURL string) (*http.Respon
 	}
 
 	// Create Redirect request
-	req, err := http.NewRequest(hook.RequestMethod, url.String(), bytes.NewBuffer(hook.Payload))
+	req, err := http.NewRequestWithContext(ctx, hook.RequestMethod, url.String(), bytes.NewBuffer(hook.Payload))
 
 	if err != nil {
 		return nil, err
@@ -109,103 +283,297 @@ func (p *Proxy) redirect(hook *providers.Hook, redirectURL string) (*http.Respon
 		req.Header.Add(key, value)
 	}
 
+	// Continue the trace into instrumented upstreams such as Jenkins
+	tracing.Inject(ctx, req.Header)
+
+	if authenticator != nil {
+		if err := authenticator.Authenticate(req); err != nil {
+			return nil, errors.New("Error authenticating to upstream: " + err.Error())
+		}
+	}
+
 	return httpClient.Do(req)
 
 }
 
-func (p *Proxy) proxyRequest(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	redirectURL := p.upstreamURL + r.URL.Path
-
-	if r.URL.RawQuery != "" {
-		redirectURL += "?" + r.URL.RawQuery
+// withoutURLQuery strips the query from the URL of a *url.Error, since it may
+// carry the values injected into the upstream query, such as tokens
+func withoutURLQuery(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
 	}
+	stripped := *urlErr
+	stripped.URL, _, _ = strings.Cut(urlErr.URL, "?")
+	return &stripped
+}
 
-	log.Printf("Proxying Request from '%s', to upstream '%s'\n", r.URL, redirectURL)
+// validate checks the hook signature within its own span, and records which
+// secret matched on the delivery
+func (p *Proxy) validate(ctx context.Context, d *delivery, provider providers.Provider, hook *providers.Hook) bool {
+	_, span := tracing.Tracer().Start(ctx, "provider.Validate",
+		trace.WithAttributes(tracing.ProviderKey.String(provider.GetProviderName())))
+	defer span.End()
 
-	if !p.isPathAllowed(r.URL.Path) {
-		log.Printf("Not allowed to proxy path: '%s'", r.URL.Path)
+	index, ok := provider.MatchSecret(*hook)
+	if !ok {
+		span.SetStatus(codes.Error, "Hook validation failed")
+		return false
+	}
+	d.validated(providers.SecretName(index))
+	return true
+}
+
+func (p *Proxy) proxyRequest(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	ctx, span := tracing.Tracer().Start(tracing.Extract(r.Context(), r.Header), "proxyRequest",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(tracing.ProviderKey.String(p.provider)))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	d := newDelivery(span, p.provider, r.URL.Path)
+	defer p.recordDelivery(d)
+	route, allowed := p.isPathAllowed(r.URL.Path)
+	if !allowed {
+		d.decide(slog.LevelWarn, logging.DecisionRejected, "Not allowed to proxy path", nil)
 		http.Error(w, "Not allowed to proxy path: '"+r.URL.Path+"'", http.StatusForbidden)
 		return
 	}
 
-	provider, err := providers.NewProvider(p.provider, p.secret)
+	// Sources are checked before anything is parsed from the request
+	if source, allowed, err := p.isSourceAllowed(route, r); !allowed {
+		if source.IsValid() {
+			d.logger = d.logger.With(logging.SourceKey, source.String())
+		}
+		d.decide(slog.LevelWarn, logging.DecisionRejected, "Source address not allowed", err)
+		http.Error(w, "Source address not allowed", http.StatusForbidden)
+		return
+	}
+
+	redirectURL := p.upstreamURLFor(route, r.URL.Path, r.URL.RawQuery)
+	// Query strings may carry upstream tokens, so only the path is logged
+	d.logger.Debug("Proxying request", "upstream", strings.SplitN(redirectURL, "?", 2)[0])
+
+	secret, previousSecrets := p.currentSecrets()
+	provider, err := providers.NewProvider(p.provider, secret, previousSecrets...)
 	if err != nil {
-		log.Printf("Error creating provider: %s", err)
+		d.decide(slog.LevelError, logging.DecisionFailed, "Error creating provider", err)
 		http.Error(w, "Error creating Provider", http.StatusInternalServerError)
 		return
 	}
 
+	p.limitBody(w, r)
 	hook, err := parser.Parse(r, provider)
+	if isBodyTooLarge(err) {
+		d.decide(slog.LevelWarn, logging.DecisionRejected, "Hook payload too large", nil)
+		http.Error(w, "Hook payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
-		log.Printf("Error Parsing Hook: %s", err)
+		d.decide(slog.LevelWarn, logging.DecisionRejected, "Error parsing hook", err)
 		http.Error(w, "Error parsing Hook: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if p.forwardsAllHeaders(route) {
+		copyHeaders(hook, r.Header)
+	}
+
 	committer := provider.GetCommitter(*hook)
-	log.Printf("Incoming request from user: %s", committer)
-	if p.isIgnoredUser(committer) || (!p.isAllowedUser(committer)) {
-		log.Printf("Ignoring request for user: %s", committer)
+	d.identify(provider, hook, committer)
+	if redaction := p.redactionFor(route); redaction != nil {
+		d.redact(redaction)
+	}
+	d.logger.Debug("Incoming request")
+
+	if !p.isUserAllowed(route, committer) {
+		d.decide(slog.LevelInfo, logging.DecisionIgnored, "Ignoring request for user", nil)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(fmt.Sprintf("Ignoring request for user: %s", committer)))
 		return
 	}
 
-	if len(strings.TrimSpace(p.secret)) > 0 && !provider.Validate(*hook) {
-		log.Printf("Error Validating Hook: %v", err)
+	validated := len(strings.TrimSpace(secret)) > 0
+	if validated && !p.validate(ctx, d, provider, hook) {
+		d.decide(slog.LevelWarn, logging.DecisionRejected, "Error validating hook", nil)
 		http.Error(w, "Error validating Hook", http.StatusBadRequest)
 		return
 	}
 
-	resp, errs := p.redirect(hook, redirectURL)
-	if errs != nil {
-		log.Printf("Error Redirecting '%s' to upstream '%s': %s\n", r.URL, redirectURL, errs)
+	reason, err := p.checkReplay(provider, hook, validated, time.Now())
+	if err != nil {
+		d.decide(slog.LevelError, logging.DecisionFailed, "Error checking replayed deliveries", err)
+		http.Error(w, "Error checking replayed deliveries", http.StatusInternalServerError)
+		return
+	}
+	if reason != "" {
+		d.decide(slog.LevelWarn, logging.DecisionRejected, reason, nil)
+		http.Error(w, "Rejecting replayed hook: "+reason, http.StatusBadRequest)
+		return
+	}
+
+	if route != nil {
+		if filter, reason := filters.Apply(route.Filters, provider, *hook); filter != nil {
+			metrics.FilteredDeliveries.WithLabelValues(filter.Name()).Inc()
+			d.decide(slog.LevelInfo, logging.DecisionIgnored, reason, nil)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Ignoring request: " + reason))
+			return
+		}
+	}
+
+	// Debounced pushes are rate limited once their window closes, so that
+	// coalesced pushes do not count
+	rateLimitKeys := p.rateLimitKeys(route, r, provider, hook, committer)
+	debounceKey, debounced := debounceKey(route, r.URL.Path, provider, hook)
+	var delay time.Duration
+	var limit *config.RateLimit
+	if !debounced {
+		var ok bool
+		delay, limit, ok = p.reserveRateLimits(route, rateLimitKeys, time.Now())
+		if !ok {
+			p.forgetDelivery(d, provider, hook)
+			metrics.RateLimitedDeliveries.WithLabelValues(limit.Key, config.RateLimitReject).Inc()
+			d.decide(slog.LevelWarn, logging.DecisionRejected, "Rate limit by "+limit.Key+" exceeded", nil)
+			w.Header().Set("Retry-After", retryAfter(delay))
+			http.Error(w, "Rate limit by "+limit.Key+" exceeded", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	// Injected values may be secrets, so responses and logs keep mentioning redirectURL
+	upstreamHook, upstreamURL, err := p.prepareUpstream(route, hook, redirectURL)
+	if err != nil {
+		p.forgetDelivery(d, provider, hook)
+		d.decide(slog.LevelError, logging.DecisionFailed, "Error preparing upstream request", err)
+		http.Error(w, "Error preparing upstream request", http.StatusInternalServerError)
+		return
+	}
+
+	if debounced {
+		p.debounce(ctx, d, route, debounceKey, rateLimitKeys, provider, hook, upstreamHook, upstreamURL)
+		d.decide(slog.LevelInfo, logging.DecisionDeferred, "Holding push for "+route.Debounce.String(), nil)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Holding push for " + route.Debounce.String()))
+		return
+	}
+
+	if delay > 0 {
+		p.forwardLater(ctx, d, route, provider, hook, upstreamHook, upstreamURL, delay)
+		metrics.RateLimitedDeliveries.WithLabelValues(limit.Key, config.RateLimitDelay).Inc()
+		d.decide(slog.LevelInfo, logging.DecisionDeferred, "Rate limit by "+limit.Key+" exceeded, deferring by "+delay.String(), nil)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Deferring request by " + delay.String()))
+		return
+	}
+
+	resp, responseBody, err := p.forward(ctx, d, route, upstreamHook, upstreamURL)
+	if resp == nil {
+		p.forgetDelivery(d, provider, hook)
+		d.decide(slog.LevelError, logging.DecisionFailed, "Error redirecting to upstream", err)
 		http.Error(w, "Error Redirecting '"+r.URL.String()+"' to upstream '"+redirectURL+"'", http.StatusInternalServerError)
 		return
 	}
 
 	if resp.StatusCode >= 400 {
-		log.Printf("Error Redirecting '%s' to upstream '%s', Upstream Redirect Status: %s\n", r.URL, redirectURL, resp.Status)
+		p.forgetDelivery(d, provider, hook)
+		d.decide(slog.LevelError, logging.DecisionFailed, "Upstream rejected redirected request", nil)
 		http.Error(w, "Error Redirecting '"+r.URL.String()+"' to upstream '"+redirectURL+"' Upstream Redirect Status:"+resp.Status, resp.StatusCode)
 		return
 	}
 
-	log.Printf("Redirected incomming request '%s' to '%s' with Response: '%s'\n",
-		r.URL, redirectURL, resp.Status)
-
-	responseBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("Error Reading upstream '%s' response body\n", r.URL)
+		d.decide(slog.LevelError, logging.DecisionFailed, "Error reading upstream response body", err)
 		http.Error(w, "Error Reading upstream '"+redirectURL+"' Response body", http.StatusInternalServerError)
 		return
 	}
 
+	d.decide(slog.LevelInfo, logging.DecisionForwarded, "Redirected request to upstream", nil)
+
 	w.WriteHeader(resp.StatusCode)
 	w.Write(responseBody)
 }
 
-// Health Check Endpoint
+// forward sends the hook upstream and records the answer on the delivery.
+// A nil response means the upstream could not be reached; a non-nil error
+// with a response means its body could not be read.
+func (p *Proxy) forward(ctx context.Context, d *delivery, route *config.Route,
+	hook *providers.Hook, redirectURL string) (*http.Response, []byte, error) {
+	start := time.Now()
+	resp, err := p.redirect(ctx, hook, redirectURL, p.authenticatorFor(route))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	d.upstreamResponded(resp.StatusCode, time.Since(start), body)
+	return resp, body, err
+}
+
+// recordDelivery stores the delivery in the history when one is configured
+func (p *Proxy) recordDelivery(d *delivery) {
+	if p.history == nil {
+		return
+	}
+	if err := p.history.Record(d.record); err != nil {
+		d.logger.Error("Error recording delivery", logging.ErrorKey, err)
+	}
+}
+
+// Health Check Endpoint, reporting every component with ?verbose=1
 func (p *Proxy) health(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose")); verbose {
+		writeJSON(w, http.StatusOK, p.healthReport(r.Context()))
+		return
+	}
+
 	w.WriteHeader(200)
 	w.Write([]byte("I'm Healthy and I know it! ;) "))
 }
 
-// Run starts Proxy server
+// Run starts Proxy server, and the admin server when one is configured
 func (p *Proxy) Run(listenAddress string) error {
 	if len(strings.TrimSpace(listenAddress)) == 0 {
 		panic("Cannot create Proxy with empty listenAddress")
 	}
 
+	errs := make(chan error, 2)
+	if p.history != nil && len(strings.TrimSpace(p.adminAddress)) > 0 {
+		slog.Info("Listening for admin requests", "address", p.adminAddress)
+		go func() { errs <- p.server(p.adminAddress, p.adminHandler()).ListenAndServe() }()
+	}
+
+	slog.Info("Listening", "address", listenAddress)
+	go func() { errs <- p.server(listenAddress, p.handler()).ListenAndServe() }()
+	return <-errs
+}
+
+// handler routes webhooks to proxyRequest, along with the health checks and
+// the metrics
+func (p *Proxy) handler() http.Handler {
 	router := httprouter.New()
 	router.GET("/health", p.health)
-	router.POST("/*path", p.proxyRequest)
+	router.GET("/ready", p.ready)
+	router.Handler(http.MethodGet, "/metrics", metrics.Handler())
+	router.POST("/*path", p.limitConcurrency(p.proxyRequest))
+	return router
+}
 
-	log.Printf("Listening at: %s", listenAddress)
-	return http.ListenAndServe(listenAddress, router)
+// adminHandler serves the delivery API and dashboard, which expose payloads
+// and redeliver hooks, so they are kept off the listener receiving webhooks
+func (p *Proxy) adminHandler() http.Handler {
+	admin := httprouter.New()
+	admin.GET("/api/deliveries", p.listDeliveries)
+	admin.GET("/api/deliveries/:id", p.getDelivery)
+	admin.GET("/ui/deliveries", p.dashboard)
+	admin.GET("/ui/deliveries/:id", p.dashboardDelivery)
+	admin.POST("/ui/deliveries/:id/redeliver", p.dashboardRedeliver)
+	return admin
 }
 
 func NewProxy(upstreamURL string, allowedPaths []string,
-	provider string, secret string, ignoredUsers []string) (*Proxy, error) {
+	provider string, secret string, ignoredUsers []string, options ...Option) (*Proxy, error) {
 	// Validate Params
 	if len(strings.TrimSpace(upstreamURL)) == 0 {
 		return nil, errors.New("Cannot create Proxy with empty upstreamURL")
@@ -216,12 +584,33 @@ func NewProxy(upstreamURL string, allowedPaths []string,
 	if allowedPaths == nil {
 		return nil, errors.New("Cannot create Proxy with nil allowedPaths")
 	}
+	ignoredUserList, err := users.Compile(ignoredUsers)
+	if err != nil {
+		return nil, errors.New("Cannot create Proxy with invalid ignoredUsers: " + err.Error())
+	}
 
-	return &Proxy{
-		provider:     provider,
-		upstreamURL:  upstreamURL,
-		allowedPaths: allowedPaths,
-		secret:       secret,
-		ignoredUsers: ignoredUsers,
-	}, nil
+	p := &Proxy{
+		provider:        provider,
+		upstreamURL:     upstreamURL,
+		allowedPaths:    allowedPaths,
+		secret:          secret,
+		ignoredUsers:    ignoredUsers,
+		ignoredUserList: ignoredUserList,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	if p.allowedUserList, err = users.Compile(p.allowedUsers); err != nil {
+		return nil, errors.New("Cannot create Proxy with invalid allowedUsers: " + err.Error())
+	}
+	if p.signsUnvalidatedHooks() {
+		return nil, errors.New("Cannot create Proxy with an upstream secret but without secret")
+	}
+	for _, route := range p.routes {
+		if len(route.RateLimits) > 0 || route.Debounce > 0 {
+			p.registerHealthCheck(queueComponent, p.deferred.check)
+			break
+		}
+	}
+	return p, nil
 }
@@ -2,15 +2,22 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	httpmock "github.com/jarcoal/httpmock"
 	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/metrics"
 	"github.com/stakater/GitWebhookProxy/pkg/providers"
+	"github.com/stakater/GitWebhookProxy/pkg/secrets"
+	"github.com/stakater/GitWebhookProxy/pkg/users"
 )
 
 const (
@@ -216,6 +223,32 @@ func TestProxy_isPathAllowed(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "isPathAllowedWithSimpleAllowedPathAndSubPathArg",
+			fields: fields{
+				provider:     providers.GithubProviderKind,
+				upstreamURL:  "https://dummyurl.com",
+				allowedPaths: []string{"/path1", "/path2"},
+				secret:       "secret",
+			},
+			args: args{
+				path: "/path2/job",
+			},
+			want: true,
+		},
+		{
+			name: "isPathAllowedWithSimpleAllowedPathAndLongerSegmentArg",
+			fields: fields{
+				provider:     providers.GithubProviderKind,
+				upstreamURL:  "https://dummyurl.com",
+				allowedPaths: []string{"/path1", "/path2"},
+				secret:       "secret",
+			},
+			args: args{
+				path: "/path2X/job",
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -225,7 +258,7 @@ func TestProxy_isPathAllowed(t *testing.T) {
 				allowedPaths: tt.fields.allowedPaths,
 				secret:       tt.fields.secret,
 			}
-			if got := p.isPathAllowed(tt.args.path); got != tt.want {
+			if _, got := p.isPathAllowed(tt.args.path); got != tt.want {
 				t.Errorf("Proxy.isPathAllowed() = %v, want %v", got, tt.want)
 			}
 		})
@@ -404,7 +437,7 @@ func TestProxy_redirect(t *testing.T) {
 				allowedPaths: tt.fields.allowedPaths,
 				secret:       tt.fields.secret,
 			}
-			gotResp, gotErrors := p.redirect(tt.args.hook, tt.args.redirectURL)
+			gotResp, gotErrors := p.redirect(context.Background(), tt.args.hook, tt.args.redirectURL, nil)
 
 			if (gotErrors != nil) != tt.wantErr {
 				t.Errorf("Parse() error = %v, wantErr %v", gotErrors, tt.wantErr)
@@ -868,10 +901,12 @@ func TestNewProxy(t *testing.T) {
 				secret:       proxyGitlabTestSecret,
 			},
 			want: &Proxy{
-				upstreamURL:  httpBinURLSecure,
-				allowedPaths: []string{},
-				provider:     providers.GitlabProviderKind,
-				secret:       proxyGitlabTestSecret,
+				upstreamURL:     httpBinURLSecure,
+				allowedPaths:    []string{},
+				provider:        providers.GitlabProviderKind,
+				secret:          proxyGitlabTestSecret,
+				ignoredUserList: createTestUserList(t, nil),
+				allowedUserList: createTestUserList(t, nil),
 			},
 		},
 		{
@@ -924,11 +959,13 @@ func TestNewProxy(t *testing.T) {
 				ignoredUsers: []string{"user1"},
 			},
 			want: &Proxy{
-				upstreamURL:  httpBinURLSecure,
-				allowedPaths: []string{"/path1", "/path2"},
-				provider:     providers.GitlabProviderKind,
-				secret:       proxyGitlabTestSecret,
-				ignoredUsers: []string{"user1"},
+				upstreamURL:     httpBinURLSecure,
+				allowedPaths:    []string{"/path1", "/path2"},
+				provider:        providers.GitlabProviderKind,
+				secret:          proxyGitlabTestSecret,
+				ignoredUsers:    []string{"user1"},
+				ignoredUserList: createTestUserList(t, []string{"user1"}),
+				allowedUserList: createTestUserList(t, nil),
 			},
 		},
 	}
@@ -991,15 +1028,51 @@ func TestProxy_isIgnoredUser(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "TestIsIgnoredUserWithGlobIgnoringCase",
+			fields: fields{
+				provider:     providers.GithubProviderKind,
+				upstreamURL:  "https://dummyurl.com",
+				allowedPaths: []string{"/path1", "/path2"},
+				secret:       "secret",
+				ignoredUsers: []string{"*-bot", "regex:^ci-[0-9]+$"},
+			},
+			args: args{
+				committer: "Jenkins-Bot",
+			},
+			want: true,
+		},
+		{
+			name: "TestIsIgnoredUserWithGroup",
+			fields: fields{
+				provider:     providers.GithubProviderKind,
+				upstreamURL:  "https://dummyurl.com",
+				allowedPaths: []string{"/path1", "/path2"},
+				secret:       "secret",
+				ignoredUsers: []string{"group:bots"},
+			},
+			args: args{
+				committer: "renovate",
+			},
+			want: true,
+		},
+	}
+	groups := users.NewGroups()
+	groupsFile := filepath.Join(t.TempDir(), "groups.yaml")
+	ioutil.WriteFile(groupsFile, []byte("bots: [renovate, dependabot]\n"), 0600)
+	if err := groups.Load(groupsFile); err != nil {
+		t.Fatal(err)
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &Proxy{
-				provider:     tt.fields.provider,
-				upstreamURL:  tt.fields.upstreamURL,
-				allowedPaths: tt.fields.allowedPaths,
-				secret:       tt.fields.secret,
-				ignoredUsers: tt.fields.ignoredUsers,
+				provider:        tt.fields.provider,
+				upstreamURL:     tt.fields.upstreamURL,
+				allowedPaths:    tt.fields.allowedPaths,
+				secret:          tt.fields.secret,
+				ignoredUsers:    tt.fields.ignoredUsers,
+				ignoredUserList: createTestUserList(t, tt.fields.ignoredUsers),
+				userGroups:      groups,
 			}
 			if got := p.isIgnoredUser(tt.args.committer); got != tt.want {
 				t.Errorf("Proxy.isIgnoredUser() = %v, want %v", got, tt.want)
@@ -1071,11 +1144,12 @@ func TestProxy_isAllowedUser(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &Proxy{
-				provider:     tt.fields.provider,
-				upstreamURL:  tt.fields.upstreamURL,
-				allowedPaths: tt.fields.allowedPaths,
-				secret:       tt.fields.secret,
-				allowedUsers: tt.fields.allowedUsers,
+				provider:        tt.fields.provider,
+				upstreamURL:     tt.fields.upstreamURL,
+				allowedPaths:    tt.fields.allowedPaths,
+				secret:          tt.fields.secret,
+				allowedUsers:    tt.fields.allowedUsers,
+				allowedUserList: createTestUserList(t, tt.fields.allowedUsers),
 			}
 			if got := p.isAllowedUser(tt.args.committer); got != tt.want {
 				t.Errorf("Proxy.isAllowedUser() = %v, want %v", got, tt.want)
@@ -1083,3 +1157,349 @@ func TestProxy_isAllowedUser(t *testing.T) {
 		})
 	}
 }
+
+func createTestUserList(t *testing.T, entries []string) *users.List {
+	list, err := users.Compile(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func createTestRoutes(t *testing.T, data string) []*config.Route {
+	cfg, err := config.Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Routes
+}
+
+func TestProxy_routeFor(t *testing.T) {
+	p := &Proxy{
+		routes: createTestRoutes(t, "routes:\n  - path: /jenkins\n  - path: /jenkins/project/\n"),
+	}
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "TestRouteForExactPath", path: "/jenkins", want: "/jenkins"},
+		{name: "TestRouteForLongestPath", path: "/jenkins/project/build", want: "/jenkins/project/"},
+		{name: "TestRouteForSubPath", path: "/jenkins/other", want: "/jenkins"},
+		{name: "TestRouteForPartialSegment", path: "/jenkinsx", want: ""},
+		{name: "TestRouteForUnknownPath", path: "/docs", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if route := p.routeFor(tt.path); route != nil {
+				got = route.Path
+			}
+			if got != tt.want {
+				t.Errorf("Proxy.routeFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxy_proxyRequestWithFilters(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	tests := []struct {
+		name         string
+		routes       string
+		allowedPaths []string
+		path         string
+		wantStatus   int
+		wantBody     string
+	}{
+		{
+			name:       "TestProxyRequestWithIncludedRef",
+			routes:     "routes:\n  - path: /post\n    refs:\n      include: [master]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "upstream says hi",
+		},
+		{
+			name:       "TestProxyRequestWithExcludedRef",
+			routes:     "routes:\n  - path: /post\n    refs:\n      exclude: [master]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "Ignoring request: Ref 'refs/heads/master' is excluded",
+		},
+		{
+			name:       "TestProxyRequestWithFilterOnOtherRoute",
+			routes:     "routes:\n  - path: /other\n    refs:\n      exclude: [master]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "upstream says hi",
+		},
+		{
+			name:       "TestProxyRequestWithChangedFileIncluded",
+			routes:     "routes:\n  - path: /post\n    files:\n      include: [app/**]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "upstream says hi",
+		},
+		{
+			// The payload lists 2 of its 4 commits, so its changed files are unknown
+			name:       "TestProxyRequestWithTruncatedChangedFiles",
+			routes:     "routes:\n  - path: /post\n    files:\n      include: [services/billing/**]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "upstream says hi",
+		},
+		{
+			name:       "TestProxyRequestWithSkipCIToken",
+			routes:     "routes:\n  - path: /post\n    skipCI:\n      tokens: [fixed]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "Ignoring request: Head commit message contains 'fixed'",
+		},
+		{
+			name:       "TestProxyRequestWithDeniedEvent",
+			routes:     "routes:\n  - path: /post\n    events:\n      deny: [push hook]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "Ignoring request: Event 'push hook' is denied",
+		},
+		{
+			name:       "TestProxyRequestWithAllowRule",
+			routes:     "routes:\n  - path: /post\n    rules:\n      allow: [\"payload.total_commits_count > 10\"]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "Ignoring request: No rule allows the hook",
+		},
+		{
+			name:       "TestProxyRequestWithRouteIgnoredUser",
+			routes:     "routes:\n  - path: /post\n    users:\n      ignore: [JSMITH]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "Ignoring request for user: jsmith",
+		},
+		{
+			name:       "TestProxyRequestWithRouteNotAllowedUser",
+			routes:     "routes:\n  - path: /post\n    users:\n      allow: [release-*]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "Ignoring request for user: jsmith",
+		},
+		{
+			name:       "TestProxyRequestWithRouteAllowedUser",
+			routes:     "routes:\n  - path: /post\n    users:\n      allow: [\"regex:^j\"]\n",
+			path:       "/post",
+			wantStatus: http.StatusOK,
+			wantBody:   "upstream says hi",
+		},
+		{
+			name:         "TestProxyRequestWithRoutePathAllowed",
+			routes:       "routes:\n  - path: /other\n",
+			allowedPaths: []string{"/post"},
+			path:         "/other/job",
+			wantStatus:   http.StatusOK,
+			wantBody:     "upstream says hi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowedPaths := []string{}
+			if tt.allowedPaths != nil {
+				allowedPaths = tt.allowedPaths
+			}
+			p, err := NewProxy(upstream.URL, allowedPaths, providers.GitlabProviderKind,
+				proxyGitlabTestSecret, []string{}, WithRoutes(createTestRoutes(t, tt.routes)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			router := httprouter.New()
+			router.POST("/*path", p.proxyRequest)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, createGitlabRequestWithPayload(http.MethodPost, tt.path,
+				proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+
+			if rr.Code != tt.wantStatus || rr.Body.String() != tt.wantBody {
+				t.Errorf("Proxy.proxyRequest() = %v %q, want %v %q", rr.Code, rr.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestProxy_isUserAllowed(t *testing.T) {
+	routes := createTestRoutes(t, `
+routes:
+  - path: /project-a
+    users:
+      allow: ["group:release-managers"]
+  - path: /github-webhook
+    users:
+      ignore: ["*[bot]"]
+  - path: /docs
+`)
+	groups := users.NewGroups()
+	groupsFile := filepath.Join(t.TempDir(), "groups.yaml")
+	ioutil.WriteFile(groupsFile, []byte("release-managers: [alice]\n"), 0600)
+	if err := groups.Load(groupsFile); err != nil {
+		t.Fatal(err)
+	}
+	p := &Proxy{
+		provider:        providers.GithubProviderKind,
+		allowedPaths:    []string{},
+		ignoredUsers:    []string{"alice"},
+		ignoredUserList: createTestUserList(t, []string{"alice"}),
+		routes:          routes,
+		userGroups:      groups,
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		committer string
+		want      bool
+	}{
+		{name: "TestIsUserAllowedWithRouteAllowedUser", path: "/project-a", committer: "alice", want: true},
+		{name: "TestIsUserAllowedWithRouteNotAllowedUser", path: "/project-a", committer: "bob", want: false},
+		{name: "TestIsUserAllowedWithRouteIgnoredUser", path: "/github-webhook", committer: "dependabot[bot]", want: false},
+		{name: "TestIsUserAllowedWithRouteOverridingIgnoredUsers", path: "/github-webhook", committer: "alice", want: true},
+		{name: "TestIsUserAllowedWithRouteAndEmptyGithubCommitter", path: "/github-webhook", committer: "", want: false},
+		{name: "TestIsUserAllowedWithRouteWithoutPolicy", path: "/docs", committer: "alice", want: false},
+		{name: "TestIsUserAllowedWithoutRoute", path: "/other", committer: "bob", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, allowed := p.isPathAllowed(tt.path)
+			if !allowed {
+				t.Fatalf("Proxy.isPathAllowed() = false, want true")
+			}
+			if got := p.isUserAllowed(route, tt.committer); got != tt.want {
+				t.Errorf("Proxy.isUserAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxy_upstreamURLFor(t *testing.T) {
+	p := &Proxy{
+		upstreamURL: "http://jenkins:8080",
+		routes: createTestRoutes(t, `
+routes:
+  - path: /hooks/(.*)
+    match: regex
+    rewrite: /generic-webhook-trigger/invoke?token=$1
+  - path: /github
+    rewrite: /github-webhook/
+`),
+	}
+	tests := []struct {
+		name     string
+		path     string
+		rawQuery string
+		want     string
+	}{
+		{name: "TestUpstreamURLForWithoutRoute", path: "/job/build", rawQuery: "delay=0", want: "http://jenkins:8080/job/build?delay=0"},
+		{name: "TestUpstreamURLForWithRewrittenQuery", path: "/hooks/abc", want: "http://jenkins:8080/generic-webhook-trigger/invoke?token=abc"},
+		{name: "TestUpstreamURLForWithBothQueries", path: "/hooks/abc", rawQuery: "delay=0", want: "http://jenkins:8080/generic-webhook-trigger/invoke?token=abc&delay=0"},
+		{name: "TestUpstreamURLForWithRewrittenPrefix", path: "/github", want: "http://jenkins:8080/github-webhook/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, _ := p.isPathAllowed(tt.path)
+			if got := p.upstreamURLFor(route, tt.path, tt.rawQuery); got != tt.want {
+				t.Errorf("Proxy.upstreamURLFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxy_proxyRequestWithPreviousSecrets(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, proxyGitlabTestSecret, []string{},
+		WithPreviousSecrets([]string{"oldSecret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+
+	tests := []struct {
+		name           string
+		token          string
+		wantStatusCode int
+		wantSecret     string
+	}{
+		{name: "TestProxyRequestWithCurrentSecret", token: proxyGitlabTestSecret, wantStatusCode: http.StatusOK, wantSecret: providers.SecretCurrent},
+		{name: "TestProxyRequestWithPreviousSecret", token: "oldSecret", wantStatusCode: http.StatusOK, wantSecret: "previous-1"},
+		{name: "TestProxyRequestWithUnknownSecret", token: "olderSecret", wantStatusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before float64
+			if tt.wantSecret != "" {
+				before = testutil.ToFloat64(metrics.ValidatedDeliveries.WithLabelValues(providers.GitlabProviderKind, tt.wantSecret))
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, createGitlabRequestWithPayload(http.MethodPost, "/post",
+				tt.token, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("Proxy.proxyRequest() = %v %q, want %v", rr.Code, rr.Body.String(), tt.wantStatusCode)
+			}
+			if tt.wantSecret == "" {
+				return
+			}
+			after := testutil.ToFloat64(metrics.ValidatedDeliveries.WithLabelValues(providers.GitlabProviderKind, tt.wantSecret))
+			if after != before+1 {
+				t.Errorf("Proxy.proxyRequest() did not count a delivery validated with the %v secret", tt.wantSecret)
+			}
+		})
+	}
+}
+
+func TestProxy_proxyRequestWithSecretFile(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := ioutil.WriteFile(path, []byte("oldSecret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secretFile := secrets.NewFile()
+	if err := secretFile.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, "", []string{},
+		WithSecretFile(secretFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+
+	tests := []struct {
+		name           string
+		content        string
+		token          string
+		wantStatusCode int
+	}{
+		{name: "TestProxyRequestWithSecretFromFile", token: "oldSecret", wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithWrongSecret", token: proxyGitlabTestSecret, wantStatusCode: http.StatusBadRequest},
+		{name: "TestProxyRequestWithRotatedSecret", content: "newSecret\n", token: "newSecret", wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithRetiredSecret", token: "oldSecret", wantStatusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.content != "" {
+				if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+					t.Fatal(err)
+				}
+				if err := secretFile.Load(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, createGitlabRequestWithPayload(http.MethodPost, "/post",
+				tt.token, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+
+			if rr.Code != tt.wantStatusCode {
+				t.Errorf("Proxy.proxyRequest() = %v %q, want %v", rr.Code, rr.Body.String(), tt.wantStatusCode)
+			}
+		})
+	}
+}
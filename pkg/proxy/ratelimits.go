@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/network"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+// rateLimitKeys returns the values by which each rate limit of route counts hook
+func (p *Proxy) rateLimitKeys(route *config.Route, r *http.Request, provider providers.Provider,
+	hook *providers.Hook, committer string) []string {
+	if route == nil {
+		return nil
+	}
+
+	keys := make([]string, len(route.RateLimits))
+	for i, limit := range route.RateLimits {
+		switch limit.Key {
+		case config.RateLimitByRepository:
+			keys[i] = provider.GetRepository(*hook)
+		case config.RateLimitBySender:
+			keys[i] = committer
+		default:
+			if source, err := network.ClientAddr(r, p.trustedProxies); err == nil {
+				keys[i] = source.String()
+			}
+		}
+	}
+	return keys
+}
+
+// reserveRateLimits takes a token from every rate limit of route for the keys
+// of a hook, and returns how long the hook must be delayed along with the
+// limit causing the delay. When a limit is exceeded beyond its maximum delay,
+// no token is taken and ok is false. Limits whose key is unknown for the hook,
+// such as the sender of GitLab merge requests, do not count it, rather than
+// counting every such hook in one shared bucket.
+func (p *Proxy) reserveRateLimits(route *config.Route, keys []string, now time.Time) (delay time.Duration, exceeded *config.RateLimit, ok bool) {
+	if route == nil {
+		return 0, nil, true
+	}
+
+	cancels := []func(){}
+	for i, limit := range route.RateLimits {
+		if keys[i] == "" {
+			continue
+		}
+		limitDelay, cancel, ok := limit.Limiter.Reserve(keys[i], now, limit.MaxDelay)
+		if !ok {
+			for _, cancel := range cancels {
+				cancel()
+			}
+			return limitDelay, limit, false
+		}
+		cancels = append(cancels, cancel)
+		if limitDelay > delay {
+			delay, exceeded = limitDelay, limit
+		}
+	}
+	return delay, exceeded, true
+}
+
+// retryAfter formats delay as the seconds of a Retry-After header
+func retryAfter(delay time.Duration) string {
+	return strconv.FormatFloat(math.Ceil(delay.Seconds()), 'f', 0, 64)
+}
+
+// forwardLater forwards the hook of the deferred delivery d once delay
+// elapsed, as a delivery of its own
+func (p *Proxy) forwardLater(ctx context.Context, d *delivery, route *config.Route, provider providers.Provider,
+	hook *providers.Hook, upstreamHook *providers.Hook, upstreamURL string, delay time.Duration) {
+	followUp := d.followUp(ctx, "Proxy.forwardLater")
+	p.deferred.after(delay, func() {
+		ctx, later := followUp()
+		defer later.span.End()
+		defer p.recordDelivery(later)
+
+		p.forwardDetached(ctx, later, route, provider, hook, upstreamHook, upstreamURL, "deferred")
+	})
+}
+
+// forwardDetached forwards the hook of d, whose request was answered already,
+// and decides the delivery from the upstream answer. kind names the hook in
+// the reason, such as "deferred".
+func (p *Proxy) forwardDetached(ctx context.Context, d *delivery, route *config.Route, provider providers.Provider,
+	hook *providers.Hook, upstreamHook *providers.Hook, upstreamURL string, kind string) {
+	resp, _, err := p.forward(ctx, d, route, upstreamHook, upstreamURL)
+	switch {
+	case resp == nil:
+		p.forgetDelivery(d, provider, hook)
+		d.decide(slog.LevelError, logging.DecisionFailed, "Error redirecting "+kind+" request to upstream", err)
+	case resp.StatusCode >= 400:
+		p.forgetDelivery(d, provider, hook)
+		d.decide(slog.LevelError, logging.DecisionFailed, "Upstream rejected "+kind+" request", nil)
+	default:
+		d.decide(slog.LevelInfo, logging.DecisionForwarded, "Redirected "+kind+" request to upstream", nil)
+	}
+}
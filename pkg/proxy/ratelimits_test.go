@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestProxy_proxyRequestWithRateLimits(t *testing.T) {
+	tests := []struct {
+		name            string
+		rateLimits      string
+		event           providers.Event
+		remoteAddrs     []string
+		wantStatusCodes []int
+		wantRetryAfter  string
+		wantForwarded   int32
+	}{
+		{
+			name:            "TestProxyRequestWithinRateLimit",
+			rateLimits:      "[{key: repository, limit: 2, per: 1h, burst: 2}]",
+			remoteAddrs:     []string{"192.0.2.1:1234", "192.0.2.1:1234"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusOK},
+			wantForwarded:   2,
+		},
+		{
+			name:            "TestProxyRequestAboveRateLimit",
+			rateLimits:      "[{key: repository, limit: 1, per: 1h}]",
+			remoteAddrs:     []string{"192.0.2.1:1234", "192.0.2.2:1234"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusTooManyRequests},
+			wantRetryAfter:  "3600",
+			wantForwarded:   1,
+		},
+		{
+			name:            "TestProxyRequestAboveRateLimitBySource",
+			rateLimits:      "[{key: source, limit: 1, per: 1h}]",
+			remoteAddrs:     []string{"192.0.2.1:1234", "192.0.2.2:1234", "192.0.2.1:1234"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			wantRetryAfter:  "3600",
+			wantForwarded:   2,
+		},
+		{
+			name:            "TestProxyRequestAboveSeveralRateLimits",
+			rateLimits:      "[{key: source, limit: 2, per: 1h, burst: 2}, {key: sender, limit: 1, per: 1h}]",
+			remoteAddrs:     []string{"192.0.2.1:1234", "192.0.2.1:1234"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusTooManyRequests},
+			wantRetryAfter:  "3600",
+			wantForwarded:   1,
+		},
+		{
+			name:            "TestProxyRequestWithoutRateLimitKey",
+			rateLimits:      "[{key: sender, limit: 1, per: 1h}]",
+			event:           providers.GitlabMergeRequestEvent,
+			remoteAddrs:     []string{"192.0.2.1:1234", "192.0.2.2:1234"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusOK},
+			wantForwarded:   2,
+		},
+		{
+			name:            "TestProxyRequestDelayedByRateLimit",
+			rateLimits:      "[{key: repository, limit: 1, per: 50ms, exceeded: delay, maxDelay: 1s}]",
+			remoteAddrs:     []string{"192.0.2.1:1234", "192.0.2.1:1234", "192.0.2.1:1234"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusAccepted, http.StatusAccepted},
+			wantForwarded:   3,
+		},
+		{
+			name:            "TestProxyRequestDelayedBeyondMaxDelay",
+			rateLimits:      "[{key: repository, limit: 1, per: 1h, exceeded: delay, maxDelay: 1m}]",
+			remoteAddrs:     []string{"192.0.2.1:1234", "192.0.2.1:1234"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusTooManyRequests},
+			wantRetryAfter:  "3600",
+			wantForwarded:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwarded atomic.Int32
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded.Add(1)
+			}))
+			defer upstream.Close()
+
+			cfg, err := config.Parse([]byte("routes:\n  - path: /gitlab\n    rateLimits: " + tt.rateLimits + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, "", []string{},
+				WithRoutes(cfg.Routes))
+			if err != nil {
+				t.Fatal(err)
+			}
+			router := httprouter.New()
+			router.POST("/*path", p.proxyRequest)
+
+			event := tt.event
+			if event == "" {
+				event = providers.GitlabPushEvent
+			}
+			for i, remoteAddr := range tt.remoteAddrs {
+				req := createGitlabRequestWithPayload(http.MethodPost, "/gitlab",
+					proxyGitlabTestSecret, string(event), proxyGitlabTestPayload)
+				req.RemoteAddr = remoteAddr
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, req)
+
+				if rr.Code != tt.wantStatusCodes[i] {
+					t.Errorf("Proxy.proxyRequest() #%v = %v %q, want %v", i, rr.Code, rr.Body.String(), tt.wantStatusCodes[i])
+				}
+				if rr.Code == http.StatusTooManyRequests && rr.Header().Get("Retry-After") != tt.wantRetryAfter {
+					t.Errorf("Proxy.proxyRequest() #%v Retry-After = %v, want %v", i, rr.Header().Get("Retry-After"), tt.wantRetryAfter)
+				}
+			}
+
+			p.deferred.wait()
+			if got := forwarded.Load(); got != tt.wantForwarded {
+				t.Errorf("Proxy.proxyRequest() forwarded %v hooks, want %v", got, tt.wantForwarded)
+			}
+		})
+	}
+}
+
+func TestProxy_proxyRequestRecordsDeferredDeliveries(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	cfg, err := config.Parse([]byte(`
+routes:
+  - path: /gitlab
+    rateLimits:
+      - {key: repository, limit: 1, per: 50ms, exceeded: delay, maxDelay: 1s}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := createTestHistory(t)
+	p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, "", []string{},
+		WithRoutes(cfg.Routes), WithHistory(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+
+	for i := 0; i < 2; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), createGitlabRequestWithPayload(http.MethodPost, "/gitlab",
+			proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload))
+	}
+	p.deferred.wait()
+
+	deliveries, err := store.List(history.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Deliveries are listed from the most recent
+	wantDecisions := []string{logging.DecisionForwarded, logging.DecisionDeferred, logging.DecisionForwarded}
+	if len(deliveries) != len(wantDecisions) {
+		t.Fatalf("history has %v deliveries, want %v", len(deliveries), len(wantDecisions))
+	}
+	for i, d := range deliveries {
+		if d.Decision != wantDecisions[i] {
+			t.Errorf("history[%v].Decision = %v, want %v", i, d.Decision, wantDecisions[i])
+		}
+	}
+	if deliveries[0].UpstreamStatus != http.StatusOK {
+		t.Errorf("deferred delivery upstream status = %v, want %v", deliveries[0].UpstreamStatus, http.StatusOK)
+	}
+}
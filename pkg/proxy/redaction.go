@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+)
+
+// WithRedaction redacts the payloads forwarded and recorded on paths without
+// a route redaction
+func WithRedaction(redaction *config.Redaction) Option {
+	return func(p *Proxy) {
+		p.redaction = redaction
+	}
+}
+
+func (p *Proxy) redactionFor(route *config.Route) *config.Redaction {
+	if route != nil && route.Redaction != nil {
+		return route.Redaction
+	}
+	return p.redaction
+}
+
+// providerSecret returns the current secret of the provider, to sign redacted
+// hooks again
+func (p *Proxy) providerSecret() (string, error) {
+	secret, _ := p.currentSecrets()
+	return secret, nil
+}
+
+// redact applies redaction to the payload recorded for d. A payload that
+// cannot be redacted is not recorded at all.
+func (d *delivery) redact(redaction *config.Redaction) {
+	payload, _, err := redaction.Rules.Apply([]byte(d.record.Payload))
+	if err != nil {
+		d.logger.Warn("Error redacting payload, it is not recorded", logging.ErrorKey, err)
+		d.record.Payload = ""
+		return
+	}
+	d.record.Payload = string(payload)
+}
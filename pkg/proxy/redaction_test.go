@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/history"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestProxy_proxyRequestWithRedaction(t *testing.T) {
+	payload := []byte(`{"ref":"refs/heads/main","sender":{"login":"octocat"},"commits":[{"id":"a1","author":{"email":"jane@example.com"}}],"head_commit":{"message":"Fix"}}`)
+	redacted := []byte(`{"commits":[{"author":{"email":"[REDACTED]"},"id":"a1"}],"ref":"refs/heads/main","sender":{"login":"octocat"}}`)
+	original := providers.SignaturePrefix + providers.HashPayload("forge", payload)
+
+	tests := []struct {
+		name          string
+		config        string
+		path          string
+		wantBody      []byte
+		wantSignature string
+	}{
+		{
+			name:          "TestRedactionWithoutResign",
+			config:        "redaction: {redact: [\"commits[*].author.email\"], remove: [head_commit]}",
+			path:          "/post",
+			wantBody:      redacted,
+			wantSignature: original,
+		},
+		{
+			name:          "TestRedactionWithResign",
+			config:        "redaction: {redact: [\"commits[*].author.email\"], remove: [head_commit], resign: true}",
+			path:          "/post",
+			wantBody:      redacted,
+			wantSignature: providers.SignaturePrefix + providers.HashPayload("forge", redacted),
+		},
+		{
+			name:          "TestRedactionWithUpstreamSecret",
+			config:        "upstreamSecret: atlantis\nredaction: {redact: [\"commits[*].author.email\"], remove: [head_commit], resign: true}",
+			path:          "/post",
+			wantBody:      redacted,
+			wantSignature: providers.SignaturePrefix + providers.HashPayload("atlantis", redacted),
+		},
+		{
+			name:          "TestRedactionWithoutMatchingFields",
+			config:        "redaction: {remove: [pull_request.body], resign: true}",
+			path:          "/post",
+			wantBody:      payload,
+			wantSignature: original,
+		},
+		{
+			name:          "TestRouteRedaction",
+			config:        "redaction: {remove: [commits]}\nroutes:\n  - path: /saas\n    redaction: {redact: [\"commits[*].author.email\"], remove: [head_commit]}",
+			path:          "/saas",
+			wantBody:      redacted,
+			wantSignature: original,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var receivedBody []byte
+			var receivedSignature string
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				receivedBody, _ = ioutil.ReadAll(r.Body)
+				receivedSignature = r.Header.Get(providers.XHubSignature)
+			}))
+			defer upstream.Close()
+
+			cfg, err := config.Parse([]byte(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			store := createTestHistory(t)
+			options := []Option{WithRoutes(cfg.Routes), WithRedaction(cfg.Redaction), WithHistory(store)}
+			if cfg.UpstreamSigningSecret != nil {
+				options = append(options, WithUpstreamSecret(cfg.UpstreamSigningSecret))
+			}
+			p, err := NewProxy(upstream.URL, []string{}, providers.GithubProviderKind, "forge", []string{}, options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			router := httprouter.New()
+			router.POST("/*path", p.proxyRequest)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(payload))
+			req.Header.Set(providers.ContentTypeHeader, providers.DefaultContentTypeHeaderValue)
+			req.Header.Set(providers.XGitHubEvent, string(providers.GithubPushEvent))
+			req.Header.Set(providers.XGitHubDelivery, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+			req.Header.Set(providers.XHubSignature, original)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Proxy.proxyRequest() = %v %q, want %v", rr.Code, rr.Body.String(), http.StatusOK)
+			}
+			if !bytes.Equal(receivedBody, tt.wantBody) || receivedSignature != tt.wantSignature {
+				t.Errorf("Proxy.proxyRequest() forwarded %s %s, want %s %s",
+					receivedBody, receivedSignature, tt.wantBody, tt.wantSignature)
+			}
+
+			deliveries, err := store.List(history.Query{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deliveries) != 1 || deliveries[0].Payload != string(tt.wantBody) {
+				t.Errorf("Proxy.proxyRequest() recorded %v, want payload %s", deliveries, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestProxy_proxyRequestWithRedactionOfInvalidPayload(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	cfg, err := config.Parse([]byte("redaction: {redact: [user_email]}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := createTestHistory(t)
+	p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, "", []string{},
+		WithRedaction(cfg.Redaction), WithHistory(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, createGitlabRequestWithPayload(http.MethodPost, "/post",
+		proxyGitlabTestSecret, string(providers.GitlabPushEvent), []byte("user_email=john@example.com")))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Proxy.proxyRequest() = %v, want %v", rr.Code, http.StatusInternalServerError)
+	}
+	deliveries, err := store.List(history.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Payload != "" {
+		t.Errorf("Proxy.proxyRequest() recorded %v, want no payload", deliveries)
+	}
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/logging"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+	"github.com/stakater/GitWebhookProxy/pkg/replay"
+)
+
+// WithReplayProtection rejects the deliveries whose ID is in store, having
+// been seen within its window
+func WithReplayProtection(store *replay.Store) Option {
+	return func(p *Proxy) {
+		p.seenDeliveries = store
+	}
+}
+
+// WithTimestampTolerance rejects the hooks whose payload carries a timestamp
+// older than tolerance
+func WithTimestampTolerance(tolerance time.Duration) Option {
+	return func(p *Proxy) {
+		p.timestampTolerance = tolerance
+	}
+}
+
+// checkReplay returns why hook is rejected as a replayed delivery, if it is,
+// and remembers its delivery ID otherwise. Delivery IDs of hooks that were not
+// validated are neither checked nor remembered, as anyone could forge them.
+func (p *Proxy) checkReplay(provider providers.Provider, hook *providers.Hook, validated bool, now time.Time) (string, error) {
+	if p.timestampTolerance > 0 {
+		if timestamp, ok := provider.GetTimestamp(*hook); ok && now.Sub(timestamp) > p.timestampTolerance {
+			return "Payload timestamp is older than " + p.timestampTolerance.String(), nil
+		}
+	}
+
+	deliveryID := provider.GetDeliveryID(*hook)
+	if p.seenDeliveries == nil || deliveryID == "" || !validated {
+		return "", nil
+	}
+	seen, err := p.seenDeliveries.Remember(deliveryID, now)
+	if err != nil {
+		return "", err
+	}
+	if seen {
+		return "Delivery was already received", nil
+	}
+	return "", nil
+}
+
+// forgetDelivery lets a delivery that could not be forwarded be received again
+func (p *Proxy) forgetDelivery(d *delivery, provider providers.Provider, hook *providers.Hook) {
+	deliveryID := provider.GetDeliveryID(*hook)
+	if p.seenDeliveries == nil || deliveryID == "" {
+		return
+	}
+	if err := p.seenDeliveries.Forget(deliveryID); err != nil {
+		d.logger.Warn("Error forgetting delivery", logging.ErrorKey, err)
+	}
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+	"github.com/stakater/GitWebhookProxy/pkg/replay"
+)
+
+func createTestReplayStore(t *testing.T) *replay.Store {
+	store, err := replay.Open(filepath.Join(t.TempDir(), "seen.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestProxy_proxyRequestWithReplayProtection(t *testing.T) {
+	upstreamStatus := http.StatusOK
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(upstreamStatus)
+	}))
+	t.Cleanup(upstream.Close)
+
+	p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, proxyGitlabTestSecret, []string{},
+		WithReplayProtection(createTestReplayStore(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+
+	tests := []struct {
+		name           string
+		uuid           string
+		token          string
+		upstreamStatus int
+		wantStatusCode int
+	}{
+		{name: "TestProxyRequestWithNewDelivery", uuid: "a", token: proxyGitlabTestSecret, upstreamStatus: http.StatusOK, wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithReplayedDelivery", uuid: "a", token: proxyGitlabTestSecret, upstreamStatus: http.StatusOK, wantStatusCode: http.StatusBadRequest},
+		{name: "TestProxyRequestWithInvalidDelivery", uuid: "b", token: "InvalidSecret", upstreamStatus: http.StatusOK, wantStatusCode: http.StatusBadRequest},
+		{name: "TestProxyRequestWithDeliveryOfInvalidOne", uuid: "b", token: proxyGitlabTestSecret, upstreamStatus: http.StatusOK, wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithFailedDelivery", uuid: "c", token: proxyGitlabTestSecret, upstreamStatus: http.StatusServiceUnavailable, wantStatusCode: http.StatusServiceUnavailable},
+		{name: "TestProxyRequestWithRetriedDelivery", uuid: "c", token: proxyGitlabTestSecret, upstreamStatus: http.StatusOK, wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithoutDeliveryID", token: proxyGitlabTestSecret, upstreamStatus: http.StatusOK, wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithoutDeliveryIDAgain", token: proxyGitlabTestSecret, upstreamStatus: http.StatusOK, wantStatusCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstreamStatus = tt.upstreamStatus
+			req := createGitlabRequestWithPayload(http.MethodPost, "/post",
+				tt.token, string(providers.GitlabPushEvent), proxyGitlabTestPayload)
+			if tt.uuid != "" {
+				req.Header.Set(providers.XGitlabEventUUID, tt.uuid)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Errorf("Proxy.proxyRequest() = %v %q, want %v", rr.Code, rr.Body.String(), tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestProxy_proxyRequestWithReplayProtectionWithoutSecret(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	store := createTestReplayStore(t)
+	p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, "", []string{},
+		WithReplayProtection(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+
+	// Unvalidated delivery IDs are not remembered, so they cannot be replayed
+	for i := 0; i < 2; i++ {
+		req := createGitlabRequestWithPayload(http.MethodPost, "/post",
+			"", string(providers.GitlabPushEvent), proxyGitlabTestPayload)
+		req.Header.Set(providers.XGitlabEventUUID, "a")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Proxy.proxyRequest() #%v = %v %q, want %v", i, rr.Code, rr.Body.String(), http.StatusOK)
+		}
+	}
+	if seen, err := store.Remember("a", time.Now()); err != nil || seen {
+		t.Errorf("Store.Remember() = %v, %v, want unseen delivery", seen, err)
+	}
+}
+
+func TestProxy_proxyRequestWithTimestampTolerance(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	p, err := NewProxy(upstream.URL, []string{}, providers.GithubProviderKind, "forge", []string{},
+		WithTimestampTolerance(5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+
+	tests := []struct {
+		name           string
+		pushedAt       time.Time
+		wantStatusCode int
+	}{
+		{name: "TestProxyRequestWithRecentPayload", pushedAt: time.Now().Add(-time.Minute), wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithOldPayload", pushedAt: time.Now().Add(-time.Hour), wantStatusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := []byte(fmt.Sprintf(`{"ref":"refs/heads/main","repository":{"pushed_at":%d},"sender":{"login":"octocat"}}`,
+				tt.pushedAt.Unix()))
+			req := httptest.NewRequest(http.MethodPost, "/post", bytes.NewReader(payload))
+			req.Header.Set(providers.ContentTypeHeader, providers.DefaultContentTypeHeaderValue)
+			req.Header.Set(providers.XGitHubEvent, string(providers.GithubPushEvent))
+			req.Header.Set(providers.XGitHubDelivery, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+			req.Header.Set(providers.XHubSignature, providers.SignaturePrefix+providers.HashPayload("forge", payload))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Errorf("Proxy.proxyRequest() = %v %q, want %v", rr.Code, rr.Body.String(), tt.wantStatusCode)
+			}
+		})
+	}
+}
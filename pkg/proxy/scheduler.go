@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// scheduler runs the forwards of deferred hooks once their delay elapsed, and
+// holds hooks by key to forward only the latest one of a debounce window
+type scheduler struct {
+	pending sync.WaitGroup
+
+	mutex   sync.Mutex
+	delayed int
+	held    map[string]*heldHook
+}
+
+// heldHook is the latest hook held for a key
+type heldHook struct {
+	// forward is called when the window of the key closes
+	forward func()
+	// coalesce is called when a later hook is held for the key instead
+	coalesce func()
+}
+
+// after runs forward in its own goroutine once delay elapsed, counting it as
+// delayed until then
+func (s *scheduler) after(delay time.Duration, forward func()) {
+	s.mutex.Lock()
+	s.delayed++
+	s.mutex.Unlock()
+
+	s.schedule(delay, func() {
+		s.mutex.Lock()
+		s.delayed--
+		s.mutex.Unlock()
+
+		forward()
+	})
+}
+
+// schedule runs fn in its own goroutine once delay elapsed
+func (s *scheduler) schedule(delay time.Duration, fn func()) {
+	s.pending.Add(1)
+	time.AfterFunc(delay, func() {
+		defer s.pending.Done()
+		fn()
+	})
+}
+
+// hold keeps hook until window elapsed since the first hook held for key,
+// then forwards the latest hook held for key. The hook it replaces, if any, is
+// coalesced.
+func (s *scheduler) hold(key string, window time.Duration, hook *heldHook) {
+	s.mutex.Lock()
+	if s.held == nil {
+		s.held = map[string]*heldHook{}
+	}
+	previous, holding := s.held[key]
+	s.held[key] = hook
+	s.mutex.Unlock()
+
+	if holding {
+		previous.coalesce()
+		return
+	}
+	s.schedule(window, func() {
+		s.mutex.Lock()
+		latest := s.held[key]
+		delete(s.held, key)
+		s.mutex.Unlock()
+
+		latest.forward()
+	})
+}
+
+// wait blocks until every scheduled forward has run
+func (s *scheduler) wait() {
+	s.pending.Wait()
+}
+
+// check reports the number of delayed and held hooks waiting to be forwarded
+func (s *scheduler) check(ctx context.Context) componentStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return componentStatus{
+		Status: StatusOK,
+		Details: map[string]interface{}{
+			"delayed": s.delayed,
+			"held":    len(s.held),
+		},
+	}
+}
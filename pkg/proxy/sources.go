@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/network"
+)
+
+// WithSourceRanges only accepts the webhooks sent from allowlist on paths
+// without route source ranges
+func WithSourceRanges(allowlist *network.Allowlist) Option {
+	return func(p *Proxy) {
+		p.sourceRanges = allowlist
+	}
+}
+
+// WithTrustedProxies finds the source of the webhooks received through
+// trustedProxies in their X-Forwarded-For header
+func WithTrustedProxies(trustedProxies *network.Allowlist) Option {
+	return func(p *Proxy) {
+		p.trustedProxies = trustedProxies
+	}
+}
+
+func (p *Proxy) sourceRangesFor(route *config.Route) *network.Allowlist {
+	if route != nil && route.SourceRanges != nil {
+		return route.SourceRanges.Allowlist
+	}
+	return p.sourceRanges
+}
+
+// isSourceAllowed returns the source address of r and whether webhooks sent
+// from it are accepted on route. Any source is accepted without source ranges.
+func (p *Proxy) isSourceAllowed(route *config.Route, r *http.Request) (netip.Addr, bool, error) {
+	allowlist := p.sourceRangesFor(route)
+	if allowlist == nil {
+		return netip.Addr{}, true, nil
+	}
+
+	source, err := network.ClientAddr(r, p.trustedProxies)
+	if err != nil {
+		return netip.Addr{}, false, err
+	}
+	return source, allowlist.Contains(source), nil
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/network"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+func TestProxy_proxyRequestWithSourceRanges(t *testing.T) {
+	upstream := createTestUpstream(t, http.StatusOK)
+	cfg, err := config.Parse([]byte(`
+sourceRanges:
+  allow: [140.82.112.0/20]
+trustedProxies: [10.0.0.0/8]
+routes:
+  - path: /gitlab
+    sourceRanges:
+      allow: [203.0.113.0/24]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProxy(upstream.URL, []string{}, providers.GitlabProviderKind, proxyGitlabTestSecret, []string{},
+		WithRoutes(cfg.Routes), WithSourceRanges(cfg.SourceRanges.Allowlist), WithTrustedProxies(cfg.TrustedProxyRanges))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	router.POST("/*path", p.proxyRequest)
+
+	tests := []struct {
+		name           string
+		path           string
+		remoteAddr     string
+		forwardedFor   string
+		wantStatusCode int
+	}{
+		{name: "TestProxyRequestFromAllowedSource", path: "/post", remoteAddr: "140.82.115.4:41234", wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestFromUnknownSource", path: "/post", remoteAddr: "198.51.100.7:41234", wantStatusCode: http.StatusForbidden},
+		{name: "TestProxyRequestThroughTrustedProxy", path: "/post", remoteAddr: "10.0.0.5:41234", forwardedFor: "140.82.115.4", wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestWithSpoofedForwardedFor", path: "/post", remoteAddr: "198.51.100.7:41234", forwardedFor: "140.82.115.4", wantStatusCode: http.StatusForbidden},
+		{name: "TestProxyRequestWithInvalidForwardedFor", path: "/post", remoteAddr: "10.0.0.5:41234", forwardedFor: "unknown", wantStatusCode: http.StatusForbidden},
+		{name: "TestProxyRequestFromRouteSource", path: "/gitlab", remoteAddr: "203.0.113.9:41234", wantStatusCode: http.StatusOK},
+		{name: "TestProxyRequestFromGlobalSourceOnRoute", path: "/gitlab", remoteAddr: "140.82.115.4:41234", wantStatusCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createGitlabRequestWithPayload(http.MethodPost, tt.path,
+				proxyGitlabTestSecret, string(providers.GitlabPushEvent), proxyGitlabTestPayload)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set(network.ForwardedForHeader, tt.forwardedFor)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Errorf("Proxy.proxyRequest() = %v %q, want %v", rr.Code, rr.Body.String(), tt.wantStatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/stakater/GitWebhookProxy/pkg/auth"
+	"github.com/stakater/GitWebhookProxy/pkg/config"
+	"github.com/stakater/GitWebhookProxy/pkg/providers"
+)
+
+// hopHeaders are not forwarded with the original headers, since they only
+// apply to the connection with the proxy
+var hopHeaders = []string{
+	"Connection",
+	"Content-Length",
+	"Host",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// WithForwardAllHeaders forwards every header of the incoming request instead
+// of the headers of the provider only
+func WithForwardAllHeaders(forwardAllHeaders bool) Option {
+	return func(p *Proxy) {
+		p.forwardAllHeaders = forwardAllHeaders
+	}
+}
+
+// WithUpstreamAuth authenticates the requests forwarded on paths without a
+// route auth
+func WithUpstreamAuth(authenticator auth.Authenticator) Option {
+	return func(p *Proxy) {
+		p.upstreamAuth = authenticator
+	}
+}
+
+// WithUpstreamSecret signs the hooks forwarded on paths without a route secret
+// again with secret, instead of forwarding the signature of the provider
+func WithUpstreamSecret(secret auth.Secret) Option {
+	return func(p *Proxy) {
+		p.upstreamSecret = secret
+	}
+}
+
+func (p *Proxy) signingSecretFor(route *config.Route) auth.Secret {
+	if route != nil && route.Upstream != nil && route.Upstream.SigningSecret != nil {
+		return route.Upstream.SigningSecret
+	}
+	return p.upstreamSecret
+}
+
+// signsUnvalidatedHooks tells whether an upstream secret is configured while
+// hooks are not validated, so that forged payloads would be signed
+func (p *Proxy) signsUnvalidatedHooks() bool {
+	if len(strings.TrimSpace(p.secret)) > 0 || p.secretFile != nil {
+		return false
+	}
+	if p.upstreamSecret != nil {
+		return true
+	}
+	for _, route := range p.routes {
+		if route.Upstream != nil && route.Upstream.SigningSecret != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Proxy) authenticatorFor(route *config.Route) auth.Authenticator {
+	if route != nil && route.Upstream != nil && route.Upstream.Auth != nil {
+		return route.Upstream.Auth.Authenticator
+	}
+	return p.upstreamAuth
+}
+
+func (p *Proxy) forwardsAllHeaders(route *config.Route) bool {
+	if route != nil && route.Upstream != nil && route.Upstream.ForwardAllHeaders != nil {
+		return *route.Upstream.ForwardAllHeaders
+	}
+	return p.forwardAllHeaders
+}
+
+// copyHeaders adds the headers of the incoming request that the hook does not
+// have yet, joining repeated values
+func copyHeaders(hook *providers.Hook, header http.Header) {
+	for key, values := range header {
+		if isHopHeader(key) || findHeader(hook.Headers, key) != "" {
+			continue
+		}
+		hook.Headers[key] = strings.Join(values, ", ")
+	}
+}
+
+func isHopHeader(key string) bool {
+	for _, hopHeader := range hopHeaders {
+		if strings.EqualFold(key, hopHeader) {
+			return true
+		}
+	}
+	return false
+}
+
+// findHeader returns the key of headers equal to key ignoring case, if any
+func findHeader(headers map[string]string, key string) string {
+	for headerKey := range headers {
+		if strings.EqualFold(headerKey, key) {
+			return headerKey
+		}
+	}
+	return ""
+}
+
+// prepareUpstream returns the hook and the URL to forward, with the payload
+// redacted, signed with the upstream secret and with the headers and the query
+// changed as configured on the route. The hook is copied, so that the recorded
+// delivery keeps the headers received.
+func (p *Proxy) prepareUpstream(route *config.Route, hook *providers.Hook, redirectURL string) (*providers.Hook, string, error) {
+	var upstream *config.Upstream
+	if route != nil {
+		upstream = route.Upstream
+	}
+	signingSecret := p.signingSecretFor(route)
+	redaction := p.redactionFor(route)
+	if upstream == nil && signingSecret == nil && redaction == nil {
+		return hook, redirectURL, nil
+	}
+
+	upstreamHook := *hook
+	upstreamHook.Headers = map[string]string{}
+	for key, value := range hook.Headers {
+		upstreamHook.Headers[key] = value
+	}
+
+	// Redacting first lets the redacted payload be signed
+	if redaction != nil {
+		payload, changed, err := redaction.Rules.Apply(hook.Payload)
+		if err != nil {
+			return nil, "", err
+		}
+		upstreamHook.Payload = payload
+		if changed && redaction.Resign && signingSecret == nil {
+			signingSecret = p.providerSecret
+		}
+	}
+
+	// Signing first lets the headers of the route remove or override the signature
+	if signingSecret != nil {
+		if err := p.sign(&upstreamHook, signingSecret); err != nil {
+			return nil, "", err
+		}
+	}
+	if upstream == nil {
+		return &upstreamHook, redirectURL, nil
+	}
+
+	set, add, err := upstream.Headers.Render()
+	if err != nil {
+		return nil, "", err
+	}
+	for _, key := range upstream.Headers.Removed() {
+		delete(upstreamHook.Headers, findHeader(upstreamHook.Headers, key))
+	}
+	for key, value := range set {
+		delete(upstreamHook.Headers, findHeader(upstreamHook.Headers, key))
+		upstreamHook.Headers[key] = value
+	}
+	for key, value := range add {
+		if existing := findHeader(upstreamHook.Headers, key); existing != "" {
+			upstreamHook.Headers[existing] += ", " + value
+			continue
+		}
+		upstreamHook.Headers[key] = value
+	}
+
+	if upstream.Query == nil {
+		return &upstreamHook, redirectURL, nil
+	}
+	upstreamURL, err := url.Parse(redirectURL)
+	if err != nil {
+		return nil, "", err
+	}
+	set, add, err = upstream.Query.Render()
+	if err != nil {
+		return nil, "", err
+	}
+	query := upstreamURL.Query()
+	for _, key := range upstream.Query.Removed() {
+		query.Del(key)
+	}
+	for key, value := range set {
+		query.Set(key, value)
+	}
+	for key, value := range add {
+		query.Add(key, value)
+	}
+	upstreamURL.RawQuery = query.Encode()
+	return &upstreamHook, upstreamURL.String(), nil
+}
+
+// sign replaces the signature of the provider on hook with one computed from
+// secret, so that the upstream and the provider do not share a secret
+func (p *Proxy) sign(hook *providers.Hook, secret auth.Secret) error {
+	value, err := secret()
+	if err != nil {
+		return err
+	}
+	provider, err := providers.NewProvider(p.provider, value)
+	if err != nil {
+		return err
+	}
+	provider.Sign(hook)
+	return nil
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// pruneInterval is the minimum interval between two removals of the buckets
+// that are full again, so that keys seen once do not use memory forever
+const pruneInterval = time.Minute
+
+// Limiter keeps a token bucket per key, such as a repository, refilled with
+// limit tokens every period and holding up to burst tokens
+type Limiter struct {
+	limit rate.Limit
+	burst int
+
+	mutex      sync.Mutex
+	buckets    map[string]*rate.Limiter
+	lastPruned time.Time
+}
+
+// NewLimiter creates a Limiter allowing limit hooks per period for each key,
+// with bursts of up to burst hooks
+func NewLimiter(limit int, per time.Duration, burst int) *Limiter {
+	return &Limiter{
+		limit:   rate.Limit(float64(limit) / per.Seconds()),
+		burst:   burst,
+		buckets: map[string]*rate.Limiter{},
+	}
+}
+
+// Reserve takes a token from the bucket of key at now and returns how long to
+// wait until it is available. When the wait would exceed maxDelay, no token is
+// taken, ok is false and delay is the wait for the next token. Otherwise cancel
+// gives the token back.
+func (l *Limiter) Reserve(key string, now time.Time, maxDelay time.Duration) (delay time.Duration, cancel func(), ok bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.prune(now)
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = rate.NewLimiter(l.limit, l.burst)
+		l.buckets[key] = bucket
+	}
+
+	reservation := bucket.ReserveN(now, 1)
+	delay = reservation.DelayFrom(now)
+	if delay > maxDelay {
+		reservation.CancelAt(now)
+		return delay, nil, false
+	}
+	return delay, func() { reservation.CancelAt(now) }, true
+}
+
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPruned) < pruneInterval {
+		return
+	}
+	l.lastPruned = now
+	for key, bucket := range l.buckets {
+		if bucket.TokensAt(now) >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Reserve(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		burst     int
+		maxDelay  time.Duration
+		requests  []time.Duration
+		wantDelay []time.Duration
+		wantOk    []bool
+	}{
+		{
+			name:      "TestReserveWithinRate",
+			burst:     1,
+			requests:  []time.Duration{0, time.Minute, 2 * time.Minute},
+			wantDelay: []time.Duration{0, 0, 0},
+			wantOk:    []bool{true, true, true},
+		},
+		{
+			name:      "TestReserveAboveRate",
+			burst:     1,
+			requests:  []time.Duration{0, 30 * time.Second, 60 * time.Second},
+			wantDelay: []time.Duration{0, 30 * time.Second, 0},
+			wantOk:    []bool{true, false, true},
+		},
+		{
+			name:      "TestReserveWithBurst",
+			burst:     2,
+			requests:  []time.Duration{0, 0, 0},
+			wantDelay: []time.Duration{0, 0, time.Minute},
+			wantOk:    []bool{true, true, false},
+		},
+		{
+			name:      "TestReserveWithDelay",
+			burst:     1,
+			maxDelay:  90 * time.Second,
+			requests:  []time.Duration{0, 0, 0, 0},
+			wantDelay: []time.Duration{0, time.Minute, 2 * time.Minute, 2 * time.Minute},
+			wantOk:    []bool{true, true, false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(1, time.Minute, tt.burst)
+			for i, at := range tt.requests {
+				delay, _, ok := l.Reserve("org/repo", start.Add(at), tt.maxDelay)
+				if delay != tt.wantDelay[i] || ok != tt.wantOk[i] {
+					t.Errorf("Limiter.Reserve() #%v = %v %v, want %v %v", i, delay, ok, tt.wantDelay[i], tt.wantOk[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_ReserveWithSeveralKeys(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(1, time.Hour, 1)
+	if _, _, ok := l.Reserve("org/a", now, 0); !ok {
+		t.Errorf("Limiter.Reserve() of first key was limited")
+	}
+	if _, _, ok := l.Reserve("org/b", now, 0); !ok {
+		t.Errorf("Limiter.Reserve() of second key was limited by the first key")
+	}
+}
+
+func TestLimiter_ReserveCancel(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(1, time.Hour, 1)
+	_, cancel, _ := l.Reserve("org/a", now, 0)
+	cancel()
+	if _, _, ok := l.Reserve("org/a", now, 0); !ok {
+		t.Errorf("Limiter.Reserve() after cancel was limited")
+	}
+}
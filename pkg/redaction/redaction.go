@@ -0,0 +1,181 @@
+package redaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces the values of redacted fields
+const Redacted = "[REDACTED]"
+
+// step selects children of a JSON value: the field key of objects, or any
+// field with a "*" key, and the element index of arrays, or any element
+// without an index
+type step struct {
+	key     string
+	isIndex bool
+	index   int
+	any     bool
+}
+
+// path selects JSON values, and whether they are removed or redacted
+type path struct {
+	steps  []step
+	remove bool
+}
+
+// Rules redacts or removes the fields of JSON payloads selected by paths such
+// as "commits[*].author.email" or "pull_request.body"
+type Rules struct {
+	paths []path
+}
+
+// Compile parses the paths of the fields to redact and to remove. Steps are
+// separated by dots, "*" selects every field of an object, "[*]" every
+// element of an array and "[2]" its third element.
+func Compile(redact []string, remove []string) (*Rules, error) {
+	rules := &Rules{}
+	for _, list := range []struct {
+		entries []string
+		remove  bool
+	}{{redact, false}, {remove, true}} {
+		for _, entry := range list.entries {
+			steps, err := parsePath(entry)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid path '%s': %s", entry, err)
+			}
+			rules.paths = append(rules.paths, path{steps: steps, remove: list.remove})
+		}
+	}
+	return rules, nil
+}
+
+func parsePath(entry string) ([]step, error) {
+	steps := []step{}
+	for _, part := range strings.Split(strings.TrimSpace(entry), ".") {
+		key, indexes, _ := strings.Cut(part, "[")
+		if key == "" && indexes == "" {
+			return nil, errors.New("empty field")
+		}
+		if key != "" {
+			steps = append(steps, step{key: key, any: key == "*"})
+		}
+		if indexes == "" && !strings.Contains(part, "[") {
+			continue
+		}
+
+		// Every bracket but the first one still starts with "["
+		for _, index := range strings.Split("["+indexes, "[")[1:] {
+			index, closed := strings.CutSuffix(index, "]")
+			if !closed {
+				return nil, errors.New("unclosed bracket")
+			}
+			if index == "*" {
+				steps = append(steps, step{isIndex: true, any: true})
+				continue
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 {
+				return nil, errors.New("invalid index '" + index + "'")
+			}
+			steps = append(steps, step{isIndex: true, index: i})
+		}
+	}
+	return steps, nil
+}
+
+// Apply returns payload with the fields of the rules redacted or removed, and
+// whether any field was. Payloads without such fields are returned as they
+// are, while changed payloads are encoded again with their object keys sorted.
+func (r *Rules) Apply(payload []byte) ([]byte, bool, error) {
+	if len(r.paths) == 0 {
+		return payload, false, nil
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false, errors.New("Payload is not JSON: " + err.Error())
+	}
+
+	changed := false
+	for _, path := range r.paths {
+		var pathChanged bool
+		value, pathChanged = apply(value, path.steps, path.remove)
+		changed = changed || pathChanged
+	}
+	if !changed {
+		return payload, false, nil
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, false, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), true, nil
+}
+
+// apply redacts or removes the children of value selected by steps, and
+// returns value, which is a new slice when array elements were removed
+func apply(value any, steps []step, remove bool) (any, bool) {
+	current, last := steps[0], len(steps) == 1
+	changed := false
+
+	switch v := value.(type) {
+	case map[string]any:
+		if current.isIndex {
+			return value, false
+		}
+		for key, child := range v {
+			if !current.any && key != current.key {
+				continue
+			}
+			switch {
+			case last && remove:
+				delete(v, key)
+				changed = true
+			case last:
+				v[key] = Redacted
+				changed = true
+			default:
+				var childChanged bool
+				v[key], childChanged = apply(child, steps[1:], remove)
+				changed = changed || childChanged
+			}
+		}
+		return v, changed
+	case []any:
+		if !current.isIndex {
+			return value, false
+		}
+		kept := make([]any, 0, len(v))
+		for i, child := range v {
+			if !current.any && i != current.index {
+				kept = append(kept, child)
+				continue
+			}
+			switch {
+			case last && remove:
+				changed = true
+				continue
+			case last:
+				child = Redacted
+				changed = true
+			default:
+				var childChanged bool
+				child, childChanged = apply(child, steps[1:], remove)
+				changed = changed || childChanged
+			}
+			kept = append(kept, child)
+		}
+		return kept, changed
+	}
+	return value, false
+}
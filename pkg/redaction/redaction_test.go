@@ -0,0 +1,101 @@
+package redaction
+
+import (
+	"testing"
+)
+
+const testPayload = `{"ref":"refs/heads/main","commits":[{"id":"a1","author":{"name":"Jane","email":"jane@example.com"}},{"id":"b2","author":{"name":"John","email":"john@example.com"}}],"pull_request":{"title":"Fix <b>","body":"Internal notes"}}`
+
+func TestRules_Apply(t *testing.T) {
+	tests := []struct {
+		name        string
+		redact      []string
+		remove      []string
+		payload     string
+		want        string
+		wantChanged bool
+		wantErr     bool
+	}{
+		{
+			name:        "TestApplyWithArrayWildcard",
+			redact:      []string{"commits[*].author.email"},
+			payload:     testPayload,
+			want:        `{"commits":[{"author":{"email":"[REDACTED]","name":"Jane"},"id":"a1"},{"author":{"email":"[REDACTED]","name":"John"},"id":"b2"}],"pull_request":{"body":"Internal notes","title":"Fix <b>"},"ref":"refs/heads/main"}`,
+			wantChanged: true,
+		},
+		{
+			name:        "TestApplyWithRemove",
+			remove:      []string{"pull_request.body", "commits[1]"},
+			payload:     testPayload,
+			want:        `{"commits":[{"author":{"email":"jane@example.com","name":"Jane"},"id":"a1"}],"pull_request":{"title":"Fix <b>"},"ref":"refs/heads/main"}`,
+			wantChanged: true,
+		},
+		{
+			name:        "TestApplyWithKeyWildcard",
+			redact:      []string{"commits[0].*"},
+			payload:     testPayload,
+			want:        `{"commits":[{"author":"[REDACTED]","id":"[REDACTED]"},{"author":{"email":"john@example.com","name":"John"},"id":"b2"}],"pull_request":{"body":"Internal notes","title":"Fix <b>"},"ref":"refs/heads/main"}`,
+			wantChanged: true,
+		},
+		{
+			name:    "TestApplyWithoutMatchingFields",
+			redact:  []string{"merge_request.description", "ref[*]"},
+			payload: `{"ref": "refs/heads/main",  "size": 12345678901234567890}`,
+			want:    `{"ref": "refs/heads/main",  "size": 12345678901234567890}`,
+		},
+		{
+			name:        "TestApplyKeepsNumbers",
+			redact:      []string{"ref"},
+			payload:     `{"ref": "refs/heads/main", "size": 12345678901234567890}`,
+			want:        `{"ref":"[REDACTED]","size":12345678901234567890}`,
+			wantChanged: true,
+		},
+		{
+			name:    "TestApplyWithInvalidPayload",
+			redact:  []string{"ref"},
+			payload: "payload=%7B%7D",
+			wantErr: true,
+		},
+		{
+			name:    "TestApplyWithoutRules",
+			payload: "payload=%7B%7D",
+			want:    "payload=%7B%7D",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := Compile(tt.redact, tt.remove)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, changed, err := rules.Apply([]byte(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rules.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want || changed != tt.wantChanged {
+				t.Errorf("Rules.Apply() = %s %v, want %s %v", got, changed, tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "TestCompileWithNestedIndexes", path: "matrix[0][*].name"},
+		{name: "TestCompileWithEmptyPath", path: "", wantErr: true},
+		{name: "TestCompileWithEmptyField", path: "pull_request..body", wantErr: true},
+		{name: "TestCompileWithUnclosedBracket", path: "commits[*.email", wantErr: true},
+		{name: "TestCompileWithInvalidIndex", path: "commits[first]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile([]string{tt.path}, nil); (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package replay
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// pruneInterval is the minimum interval between two removals of the
+// delivery IDs seen before the window
+const pruneInterval = time.Minute
+
+var seenBucket = []byte("seen")
+
+// Store remembers the delivery IDs seen within a window in a bolt database
+// file, so that replayed deliveries are detected across restarts
+type Store struct {
+	db     *bolt.DB
+	window time.Duration
+
+	mutex      sync.Mutex
+	lastPruned time.Time
+}
+
+// Open opens or creates the seen delivery store at path, remembering delivery
+// IDs for window
+func Open(path string, window time.Duration) (*Store, error) {
+	if window <= 0 {
+		return nil, errors.New("Cannot create seen delivery store with non-positive window")
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(seenBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db, window: window}, nil
+}
+
+// Close releases the database file
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Remember records that id was seen at now, and reports whether it was
+// already seen within the window
+func (s *Store) Remember(id string, now time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	seen := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(seenBucket)
+		if value := bucket.Get([]byte(id)); value != nil && now.Sub(btot(value)) < s.window {
+			seen = true
+			return nil
+		}
+		if err := bucket.Put([]byte(id), ttob(now)); err != nil {
+			return err
+		}
+
+		if now.Sub(s.lastPruned) < pruneInterval {
+			return nil
+		}
+		s.lastPruned = now
+		cursor := bucket.Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			if now.Sub(btot(value)) >= s.window {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return seen, err
+}
+
+// Forget removes id, so that a delivery that could not be forwarded can be
+// delivered again
+func (s *Store) Forget(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(seenBucket).Delete([]byte(id))
+	})
+}
+
+func ttob(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+func btot(b []byte) time.Time {
+	if len(b) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
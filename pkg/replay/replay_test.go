@@ -0,0 +1,62 @@
+package replay
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "seen.db"), 0); err == nil {
+		t.Errorf("Open() with zero window did not return an error")
+	}
+}
+
+func TestStore_Remember(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.db")
+	store, err := Open(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		id       string
+		at       time.Duration
+		wantSeen bool
+	}{
+		{name: "TestRememberWithNewID", id: "a", wantSeen: false},
+		{name: "TestRememberWithOtherID", id: "b", at: time.Minute, wantSeen: false},
+		{name: "TestRememberWithIDSeenWithinWindow", id: "a", at: 59 * time.Minute, wantSeen: true},
+		{name: "TestRememberWithIDSeenBeforeWindow", id: "a", at: 2 * time.Hour, wantSeen: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen, err := store.Remember(tt.id, start.Add(tt.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen != tt.wantSeen {
+				t.Errorf("Store.Remember(%v) = %v, want %v", tt.id, seen, tt.wantSeen)
+			}
+		})
+	}
+
+	if err := store.Forget("a"); err != nil {
+		t.Fatal(err)
+	}
+	if seen, _ := store.Remember("a", start.Add(2*time.Hour)); seen {
+		t.Errorf("Store.Remember() of forgotten id = %v, want false", seen)
+	}
+
+	// Seen ids are kept across restarts
+	store.Close()
+	if store, err = Open(path, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if seen, _ := store.Remember("a", start.Add(2*time.Hour)); !seen {
+		t.Errorf("Store.Remember() after reopening = %v, want true", seen)
+	}
+}
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/utils"
+)
+
+// ErrNoSecret is returned when a secret file does not contain any secret
+var ErrNoSecret = errors.New("Secret file does not contain any secret")
+
+// File holds the webhook secrets read from a file, such as a mounted
+// Kubernetes Secret or a file rendered by the Vault agent. Its first non-empty
+// line is the current secret, and the following ones are previous secrets
+// still accepted while rotating.
+type File struct {
+	mutex    sync.RWMutex
+	secret   string
+	previous []string
+	// loaded is the content of the file the secrets were read from
+	loaded []byte
+}
+
+func NewFile() *File {
+	return &File{}
+}
+
+// Load replaces the secrets by those of the file at path. The secrets are
+// kept when the file is invalid.
+func (f *File) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return f.parse(data)
+}
+
+func (f *File) parse(data []byte) error {
+	secrets := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if secret := strings.TrimSpace(line); len(secret) > 0 {
+			secrets = append(secrets, secret)
+		}
+	}
+	if len(secrets) == 0 {
+		return ErrNoSecret
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.secret = secrets[0]
+	f.previous = secrets[1:]
+	f.loaded = data
+	return nil
+}
+
+// Secrets returns the current secret and the previous ones
+func (f *File) Secrets() (string, []string) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.secret, f.previous
+}
+
+// Watch reloads the secrets whenever the file at path differs from the one
+// last loaded, until ctx is done, and reports the result of every reload.
+func (f *File) Watch(ctx context.Context, path string, interval time.Duration, report func(error)) {
+	f.mutex.RLock()
+	last := f.loaded
+	f.mutex.RUnlock()
+
+	utils.WatchFile(ctx, path, interval, last, f.parse, report)
+}
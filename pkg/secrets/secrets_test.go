@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func createSecretFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFile_Load(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantSecret   string
+		wantPrevious []string
+		wantErr      bool
+	}{
+		{
+			name:         "TestLoadWithSingleSecret",
+			content:      "s3cr3t\n",
+			wantSecret:   "s3cr3t",
+			wantPrevious: []string{},
+		},
+		{
+			name:         "TestLoadWithPreviousSecrets",
+			content:      "\n  new  \n\nold\nolder",
+			wantSecret:   "new",
+			wantPrevious: []string{"old", "older"},
+		},
+		{
+			name:    "TestLoadWithEmptyFile",
+			content: " \n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFile()
+			err := f.Load(createSecretFile(t, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("File.Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			secret, previous := f.Secrets()
+			if secret != tt.wantSecret || !reflect.DeepEqual(previous, tt.wantPrevious) {
+				t.Errorf("File.Secrets() = %q %q, want %q %q", secret, previous, tt.wantSecret, tt.wantPrevious)
+			}
+		})
+	}
+}
+
+func TestFile_Watch(t *testing.T) {
+	f := NewFile()
+	path := createSecretFile(t, "old\n")
+	if err := f.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan error, 1)
+	go f.Watch(ctx, path, 10*time.Millisecond, func(err error) {
+		reloaded <- err
+	})
+
+	if err := ioutil.WriteFile(path, []byte("new\nold\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatalf("File.Watch() reported error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("File.Watch() did not reload the changed file")
+	}
+
+	if secret, previous := f.Secrets(); secret != "new" || !reflect.DeepEqual(previous, []string{"old"}) {
+		t.Errorf("File.Watch() did not replace the secrets, got %q %q", secret, previous)
+	}
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	type args struct {
+		exporter string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "TestSetupWithNoneExporter",
+			args: args{
+				exporter: ExporterNone,
+			},
+		},
+		{
+			name: "TestSetupWithStdoutExporter",
+			args: args{
+				exporter: ExporterStdout,
+			},
+		},
+		{
+			name: "TestSetupWithInvalidExporter",
+			args: args{
+				exporter: "zipkin",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tt.args.exporter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Setup() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil {
+				if err := shutdown(context.Background()); err != nil {
+					t.Errorf("Setup() shutdown error = %v", err)
+				}
+			}
+		})
+	}
+}
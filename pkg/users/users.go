@@ -0,0 +1,147 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stakater/GitWebhookProxy/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// GroupPrefix marks a user list entry as a reference to a named group
+const GroupPrefix = "group:"
+
+// ErrEmptyGroupName is returned for "group:" entries without a name
+var ErrEmptyGroupName = errors.New("Group name cannot be empty")
+
+// List matches users against globs, regular expressions prefixed with
+// "regex:" and named groups prefixed with "group:", ignoring case
+type List struct {
+	patterns []*utils.Pattern
+	groups   []string
+}
+
+// Compile parses the entries of a user list
+func Compile(entries []string) (*List, error) {
+	list := &List{}
+	patterns := []string{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.HasPrefix(entry, GroupPrefix) {
+			group := strings.TrimSpace(strings.TrimPrefix(entry, GroupPrefix))
+			if group == "" {
+				return nil, ErrEmptyGroupName
+			}
+			list.groups = append(list.groups, group)
+			continue
+		}
+		patterns = append(patterns, entry)
+	}
+
+	compiled, err := utils.CompileFoldPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	list.patterns = compiled
+	return list, nil
+}
+
+// Empty tells whether the list has no entries
+func (l *List) Empty() bool {
+	return l == nil || (len(l.patterns) == 0 && len(l.groups) == 0)
+}
+
+// Match reports whether user matches one of the patterns or belongs to one
+// of the groups
+func (l *List) Match(user string, groups *Groups) bool {
+	if l == nil {
+		return false
+	}
+	if utils.MatchAny(l.patterns, user) {
+		return true
+	}
+	for _, group := range l.groups {
+		if groups.Match(group, user) {
+			return true
+		}
+	}
+	return false
+}
+
+// Groups holds named user lists, such as team rosters, that can be reloaded
+// while the proxy runs. Members are patterns, like the entries of a List.
+type Groups struct {
+	mutex  sync.RWMutex
+	groups map[string][]*utils.Pattern
+	// loaded is the content of the file the groups were loaded from
+	loaded []byte
+}
+
+func NewGroups() *Groups {
+	return &Groups{groups: map[string][]*utils.Pattern{}}
+}
+
+// Load replaces the groups by those of the YAML file at path, mapping group
+// names to their members. The groups are kept when the file is invalid.
+func (g *Groups) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return g.parse(data)
+}
+
+func (g *Groups) parse(data []byte) error {
+	members := map[string][]string{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&members); err != nil && err != io.EOF {
+		return err
+	}
+
+	groups := map[string][]*utils.Pattern{}
+	for name, groupMembers := range members {
+		patterns, err := utils.CompileFoldPatterns(groupMembers)
+		if err != nil {
+			return fmt.Errorf("Invalid group '%s': %s", name, err)
+		}
+		groups[name] = patterns
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.groups = groups
+	g.loaded = data
+	return nil
+}
+
+// Match reports whether user is a member of the named group. Unknown groups
+// have no members.
+func (g *Groups) Match(name string, user string) bool {
+	if g == nil {
+		return false
+	}
+
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return utils.MatchAny(g.groups[name], user)
+}
+
+// Watch reloads the groups whenever the file at path differs from the one
+// last loaded, until ctx is done, and reports the result of every reload.
+func (g *Groups) Watch(ctx context.Context, path string, interval time.Duration, report func(error)) {
+	g.mutex.RLock()
+	last := g.loaded
+	g.mutex.RUnlock()
+
+	utils.WatchFile(ctx, path, interval, last, g.parse, report)
+}
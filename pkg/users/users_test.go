@@ -0,0 +1,147 @@
+package users
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func createGroupsFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "groups.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestList_Match(t *testing.T) {
+	groups := NewGroups()
+	if err := groups.Load(createGroupsFile(t, "release-managers: [alice, \"regex:^rm-\"]\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		entries []string
+		user    string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "TestMatchWithExactUserIgnoringCase",
+			args: args{entries: []string{"Bob"}, user: "bob"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithGlob",
+			args: args{entries: []string{"*-bot"}, user: "deploy-bot"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithRegex",
+			args: args{entries: []string{"regex:^svc-[a-z]+$"}, user: "svc-jenkins"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithGroupMember",
+			args: args{entries: []string{"group:release-managers"}, user: "RM-carol"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithGroupNonMember",
+			args: args{entries: []string{"group:release-managers"}, user: "bob"},
+			want: false,
+		},
+		{
+			name: "TestMatchWithUnknownGroup",
+			args: args{entries: []string{"group:unknown"}, user: "alice"},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := Compile(tt.args.entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := list.Match(tt.args.user, groups); got != tt.want {
+				t.Errorf("List.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name      string
+		entries   []string
+		wantEmpty bool
+		wantErr   bool
+	}{
+		{name: "TestCompileWithEmptyEntries", entries: []string{"", " "}, wantEmpty: true},
+		{name: "TestCompileWithGroup", entries: []string{"group:team"}},
+		{name: "TestCompileWithEmptyGroupName", entries: []string{"group:"}, wantErr: true},
+		{name: "TestCompileWithInvalidRegex", entries: []string{"regex:("}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := Compile(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && list.Empty() != tt.wantEmpty {
+				t.Errorf("List.Empty() = %v, want %v", list.Empty(), tt.wantEmpty)
+			}
+		})
+	}
+}
+
+func TestGroups_Load(t *testing.T) {
+	groups := NewGroups()
+	path := createGroupsFile(t, "team: [alice]\n")
+	if err := groups.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := groups.Load(createGroupsFile(t, "team: [\"regex:(\"]\n")); err == nil {
+		t.Errorf("Groups.Load() with invalid member did not return an error")
+	}
+	if !groups.Match("team", "alice") {
+		t.Errorf("Groups.Load() with invalid file did not keep the previous groups")
+	}
+}
+
+func TestGroups_Watch(t *testing.T) {
+	groups := NewGroups()
+	path := createGroupsFile(t, "team: [alice]\n")
+	if err := groups.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan error, 1)
+	go groups.Watch(ctx, path, 10*time.Millisecond, func(err error) {
+		reloaded <- err
+	})
+
+	if err := ioutil.WriteFile(path, []byte("team: [bob]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatalf("Groups.Watch() reported error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Groups.Watch() did not reload the changed file")
+	}
+
+	if groups.Match("team", "alice") || !groups.Match("team", "bob") {
+		t.Errorf("Groups.Watch() did not replace the group members")
+	}
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RegexPrefix marks a pattern as a regular expression instead of a glob
+const RegexPrefix = "regex:"
+
+// Pattern matches strings against a glob or a regular expression. In globs
+// '*' and '?' do not match '/', while '**' matches any number of path segments.
+type Pattern struct {
+	raw string
+	re  *regexp.Regexp
+}
+
+// CompilePattern compiles a glob, or a regular expression prefixed with "regex:"
+func CompilePattern(pattern string) (*Pattern, error) {
+	return compilePattern(pattern, false)
+}
+
+// CompilePatterns compiles every pattern, failing on the first invalid one
+func CompilePatterns(patterns []string) ([]*Pattern, error) {
+	return compilePatterns(patterns, false)
+}
+
+// CompileFoldPatterns compiles every pattern to match ignoring case
+func CompileFoldPatterns(patterns []string) ([]*Pattern, error) {
+	return compilePatterns(patterns, true)
+}
+
+func compilePattern(pattern string, fold bool) (*Pattern, error) {
+	expression := globToRegex(pattern)
+	if strings.HasPrefix(pattern, RegexPrefix) {
+		expression = strings.TrimPrefix(pattern, RegexPrefix)
+	}
+	if fold {
+		expression = "(?i)" + expression
+	}
+
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{raw: pattern, re: re}, nil
+}
+
+func compilePatterns(patterns []string, fold bool) ([]*Pattern, error) {
+	compiled := make([]*Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := compilePattern(strings.TrimSpace(pattern), fold)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// Match reports whether s matches the pattern
+func (p *Pattern) Match(s string) bool {
+	return p.re.MatchString(s)
+}
+
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// MatchAny reports whether s matches at least one of patterns
+func MatchAny(patterns []*Pattern, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func globToRegex(glob string) string {
+	var expression strings.Builder
+	expression.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// Also matches no directory at all
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			expression.WriteString("[^/]*")
+		case glob[i] == '?':
+			expression.WriteString("[^/]")
+		default:
+			expression.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	expression.WriteString("$")
+	return expression.String()
+}
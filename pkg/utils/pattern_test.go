@@ -0,0 +1,90 @@
+package utils
+
+import "testing"
+
+func TestPattern_Match(t *testing.T) {
+	type args struct {
+		pattern string
+		value   string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "TestMatchWithExactGlob",
+			args: args{pattern: "main", value: "main"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithStarGlob",
+			args: args{pattern: "release/*", value: "release/1.0"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithStarGlobAcrossSegments",
+			args: args{pattern: "release/*", value: "release/1.0/hotfix"},
+			want: false,
+		},
+		{
+			name: "TestMatchWithDoubleStarGlob",
+			args: args{pattern: "services/billing/**", value: "services/billing/api/main.go"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithLeadingDoubleStarGlob",
+			args: args{pattern: "**/*.go", value: "main.go"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithQuestionMarkGlob",
+			args: args{pattern: "v?", value: "v1"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithGlobSpecialCharacters",
+			args: args{pattern: "dependabot[bot]", value: "dependabotb"},
+			want: false,
+		},
+		{
+			name: "TestMatchWithRegex",
+			args: args{pattern: "regex:^v[0-9]+\\.[0-9]+$", value: "v1.10"},
+			want: true,
+		},
+		{
+			name: "TestMatchWithNonMatchingRegex",
+			args: args{pattern: "regex:^v[0-9]+$", value: "v1.10"},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := CompilePattern(tt.args.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Match(tt.args.value); got != tt.want {
+				t.Errorf("Pattern.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePatterns(t *testing.T) {
+	if _, err := CompilePatterns([]string{"main", "regex:(unclosed"}); err == nil {
+		t.Errorf("CompilePatterns() with invalid regex did not return an error")
+	}
+}
+
+func TestCompileFoldPatterns(t *testing.T) {
+	patterns, err := CompileFoldPatterns([]string{"*-Bot", "regex:^release-.*$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"jenkins-bot", "RELEASE-manager"} {
+		if !MatchAny(patterns, value) {
+			t.Errorf("MatchAny() with fold patterns did not match %v", value)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"time"
+)
+
+// WatchFile calls load with the content of the file at path whenever it
+// differs from last, until ctx is done, and reports the result of every
+// reload. Files are polled, which also catches the symlink swaps of
+// Kubernetes ConfigMap and Secret volumes. Once a failed read is followed by
+// a successful one, the result of the last load is reported again.
+func WatchFile(ctx context.Context, path string, interval time.Duration, last []byte,
+	load func(data []byte) error, report func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var loadErr error
+	readFailed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := ioutil.ReadFile(path)
+		if err != nil {
+			readFailed = true
+			report(err)
+			continue
+		}
+		if bytes.Equal(current, last) {
+			if readFailed {
+				readFailed = false
+				report(loadErr)
+			}
+			continue
+		}
+		last, readFailed = current, false
+
+		loadErr = load(current)
+		report(loadErr)
+	}
+}
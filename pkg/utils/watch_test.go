@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFile_RecoversFromReadError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watched")
+	content := []byte("secret\n")
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan error, 100)
+	go WatchFile(ctx, path, 10*time.Millisecond, content, func(data []byte) error {
+		t.Errorf("WatchFile() loaded unchanged content %q", data)
+		return nil
+	}, func(err error) {
+		reports <- err
+	})
+
+	// The file briefly disappears, then comes back unchanged
+	if err := os.Rename(path, path+".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	waitReport := func(wantErr bool) {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case err := <-reports:
+				if (err != nil) == wantErr {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("WatchFile() did not report wantErr %v", wantErr)
+			}
+		}
+	}
+	waitReport(true)
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+	waitReport(false)
+}